@@ -0,0 +1,11 @@
+// Command gokys-vet checks WMFP effort budgets of packages. It can be run
+// standalone or by `go vet -vettool=$(which gokys-vet)`.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/bragov4ik/go-kys/pkg/analyzer"
+)
+
+func main() { singlechecker.Main(analyzer.Analyzer) }
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bragov4ik/go-kys/pkg/annotate"
+	"github.com/bragov4ik/go-kys/pkg/config"
+)
+
+// Handles `gokys annotate`: prints files with minutes of every line
+func annotateCmd(args []string) {
+	flags := flag.NewFlagSet("annotate", flag.ExitOnError)
+	cfgpath := flags.String("c", "", "Config file in xml, yaml, json or toml format (default is discovered)")
+	metrics := flags.Bool("metrics", false, "Show minutes of every metric in separate columns")
+	color := flags.String("color", "auto", "Colour hot lines: auto, always or never")
+	die(flags.Parse(args))
+	if flags.NArg() == 0 {
+		die(fmt.Errorf("usage: gokys annotate [-c config] [-metrics] [-color when] file.go..."))
+	}
+
+	opts := annotate.Options{Metrics: *metrics}
+	switch *color {
+	case "auto":
+		opts.Color = isTerminal(os.Stdout)
+	case "always":
+		opts.Color = true
+	case "never":
+	default:
+		die(fmt.Errorf("unknown -color value %q, want auto, always or never", *color))
+	}
+
+	loaded, err := config.Resolve(*cfgpath, targetDir(flags.Args()))
+	die(err)
+	for i, path := range flags.Args() {
+		cfg := loaded.For(path)
+		file, err := annotate.AnnotateFile(path, &cfg)
+		die(err)
+		if i > 0 {
+			fmt.Println()
+		}
+		die(annotate.Write(os.Stdout, file, &cfg, opts))
+	}
+}
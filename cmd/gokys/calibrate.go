@@ -0,0 +1,73 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/bragov4ik/go-kys/pkg/calibrate"
+	"github.com/bragov4ik/go-kys/pkg/config"
+)
+
+// Handles `gokys calibrate`: fits weights to minutes spent on targets from csv
+// and prints new config, goodness of fit goes to stderr
+func calibrateCmd(args []string) {
+	flags := flag.NewFlagSet("calibrate", flag.ExitOnError)
+	path := flags.String("c", "", "Base config, weights which are not fitted are taken from it (default is discovered)")
+	to := flags.String("to", "", "Output format: xml, yaml, json or toml (default is extension of output file or xml)")
+	out := flags.String("o", "", "Output file (default is stdout)")
+	die(flags.Parse(args))
+	if flags.NArg() != 1 {
+		die(fmt.Errorf("usage: gokys calibrate [-c config] [-to format] [-o output] <efforts.csv>"))
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	die(err)
+	targets, err := calibrate.ReadTargets(f)
+	f.Close()
+	die(err)
+
+	loaded, err := config.Resolve(*path, ".")
+	die(err)
+	base := loaded.Config
+
+	samples := make([]calibrate.Sample, len(targets))
+	for i, target := range targets {
+		samples[i], err = calibrate.Collect(target, &base)
+		die(err)
+	}
+	result, err := calibrate.Fit(samples)
+	die(err)
+
+	tw := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tMINUTES\tPREDICTED\t")
+	for i, s := range samples {
+		fmt.Fprintf(tw, "%s\t%.2f\t%.2f\t\n", s.Name, s.Minutes, result.Predicted[i])
+	}
+	die(tw.Flush())
+	fmt.Fprintf(os.Stderr, "\n%d samples, %d weights, R² = %.3f, RMSE = %.2f minutes (on the same samples)\n",
+		len(samples), len(result.Weights), result.R2, result.RMSE)
+	if result.Underdetermined {
+		fmt.Fprintln(os.Stderr, "warning: fewer samples than weights which affect them, fitted weights are arbitrary")
+	}
+
+	name := *to
+	if name == "" && *out != "" {
+		name = filepath.Ext(*out)
+	} else if name == "" {
+		name = "xml"
+	}
+	format, err := config.ParseFormat(name)
+	die(err)
+	cfg := calibrate.Apply(base, result.Weights)
+	data, err := config.Encode(&cfg, format)
+	die(err)
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*out, data, 0o644)
+	}
+	die(err)
+}
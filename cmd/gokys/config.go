@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+)
+
+// Handles `gokys config <command>`
+func configCmd(args []string) {
+	if len(args) == 0 {
+		die(fmt.Errorf("usage: gokys config <convert|show|validate> [flags]"))
+	}
+	switch args[0] {
+	case "convert":
+		convertCmd(args[1:])
+	case "show":
+		showCmd(args[1:])
+	case "validate":
+		validateCmd(args[1:])
+	default:
+		die(fmt.Errorf("unknown config command %q", args[0]))
+	}
+}
+
+// Translates config file to another format
+func convertCmd(args []string) {
+	flags := flag.NewFlagSet("config convert", flag.ExitOnError)
+	to := flags.String("to", "", "Output format: xml, yaml, json or toml (default is extension of output file)")
+	out := flags.String("o", "", "Output file (default is stdout)")
+	die(flags.Parse(args))
+	if flags.NArg() != 1 {
+		die(fmt.Errorf("usage: gokys config convert [-to format] [-o output] <config>"))
+	}
+
+	loaded, err := config.LoadWithDefaults(flags.Arg(0))
+	die(err)
+	for _, warning := range loaded.Warnings {
+		fmt.Fprintln(os.Stderr, "warning:", warning.Error())
+	}
+
+	name := *to
+	if name == "" {
+		if *out == "" {
+			die(fmt.Errorf("-to is required when writing to stdout"))
+		}
+		name = filepath.Ext(*out)
+	}
+	format, err := config.ParseFormat(name)
+	die(err)
+
+	data, err := loaded.Encode(format)
+	die(err)
+	if *out == "" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*out, data, 0o644)
+	}
+	die(err)
+}
+
+// Prints effective config and origin of every value
+func showCmd(args []string) {
+	flags := flag.NewFlagSet("config show", flag.ExitOnError)
+	path := flags.String("c", "", "Config file (default is discovered)")
+	die(flags.Parse(args))
+
+	loaded, err := config.Resolve(*path, targetDir(flags.Args()))
+	die(err)
+
+	source := loaded.Path
+	if source == "" {
+		source = config.DefaultSource
+	}
+	fmt.Printf("# effective config: %s\n", source)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 1, ' ', 0)
+	for _, v := range loaded.Values() {
+		fmt.Fprintf(tw, "%s = %s\t# %s\n", v.Path, v.Value, v.Source)
+	}
+	die(tw.Flush())
+}
+
+// Prints all problems of config files, fails if any of them is invalid
+func validateCmd(paths []string) {
+	if len(paths) == 0 {
+		die(fmt.Errorf("usage: gokys config validate <config>..."))
+	}
+	valid := true
+	for _, path := range paths {
+		loaded, err := config.LoadWithDefaults(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			valid = false
+			continue
+		}
+		for _, warning := range loaded.Warnings {
+			fmt.Fprintln(os.Stderr, "warning:", warning.Error())
+		}
+	}
+	if !valid {
+		os.Exit(1)
+	}
+}
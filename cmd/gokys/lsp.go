@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/lsp"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Handles `gokys lsp`: serves language server protocol over stdio
+func lspCmd(args []string) {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	cfgpath := flags.String("c", "", "Config file in xml, yaml, json or toml format (default is discovered for every file)")
+	die(flags.Parse(args))
+
+	// stdout is used by protocol, logs go to stderr which editors show in server output
+	logger := log.New(os.Stderr, "gokys lsp: ", log.LstdFlags)
+	configs := config.Cache{Path: *cfgpath}
+	server := lsp.NewServer(func(path string) wmfp.Config {
+		loaded, err := configs.Resolve(filepath.Dir(path))
+		if err != nil {
+			logger.Print(err)
+			loaded = &config.Loaded{Config: config.Default()}
+		}
+		return loaded.For(path)
+	})
+	server.Log = func(err error) { logger.Print(err) }
+	if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+		logger.Fatal(err)
+	}
+}
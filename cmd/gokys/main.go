@@ -1,33 +1,53 @@
 package main
 
 import (
-	"encoding/xml"
 	"flag"
 	"fmt"
+	"io"
 	"io/fs"
-	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 
-	"go/parser"
-	"go/token"
-
+	"github.com/bragov4ik/go-kys/pkg/analyzer"
+	"github.com/bragov4ik/go-kys/pkg/cache"
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/sarif"
+	"github.com/bragov4ik/go-kys/pkg/site"
 	"github.com/bragov4ik/go-kys/pkg/wmfp"
 )
 
-var cfgpath = flag.String("c", "config.xml", "XML config")
-
-func readCfg() wmfp.Config {
-	file, err := os.Open(*cfgpath)
-	die(err)
-	defer func(cfg *os.File) { die(cfg.Close()) }(file)
-	bytes, err := ioutil.ReadAll(file)
+var cfgpath = flag.String("c", "", "Config file in xml, yaml, json or toml format (default is discovered)")
+var format = flag.String("format", "total", "Report format: total, text, json, html or sarif")
+var output = flag.String("o", "", "Output file (default is stdout), directory of site for html format")
+var funcBudget = flag.Float64("func-budget", analyzer.DefaultSettings.Func, "Budget of every function in minutes for sarif format, 0 disables it")
+var fileBudget = flag.Float64("file-budget", analyzer.DefaultSettings.File, "Budget of every file in minutes for sarif format, 0 disables it")
+var packageBudget = flag.Float64("package-budget", analyzer.DefaultSettings.Package, "Budget of every package in minutes for sarif format, 0 disables it")
+var cacheDir = flag.String("cache-dir", "", "Directory of cache of measured files (default is gokys in user cache directory)")
+var cacheSize = flag.Int64("cache-size", cache.DefaultSize>>20, "Maximum size of cache in megabytes, 0 for no limit")
+var noCache = flag.Bool("no-cache", false, "Measure all files without reading or writing cache")
+
+// Reads config given by flag, or discovers it for the first target
+func readCfg(args []string) *config.Loaded {
+	loaded, err := config.Resolve(*cfgpath, targetDir(args))
 	die(err)
+	if len(loaded.Warnings) > 0 {
+		log.Printf("%s: %d weights are missing, using defaults (see `gokys config validate`)",
+			loaded.Path, len(loaded.Warnings))
+	}
+	return loaded
+}
 
-	var cfg wmfp.Config
-	die(xml.Unmarshal(bytes, &cfg))
-	return cfg
+// Returns directory where config discovery starts
+func targetDir(args []string) string {
+	if len(args) == 0 {
+		return "."
+	}
+	if info, err := os.Stat(args[0]); err == nil && info.IsDir() {
+		return args[0]
+	}
+	return filepath.Dir(args[0])
 }
 
 func getFiles(args []string) []string {
@@ -48,34 +68,124 @@ func getFiles(args []string) []string {
 }
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "config":
+			configCmd(os.Args[2:])
+			return
+		case "calibrate":
+			calibrateCmd(os.Args[2:])
+			return
+		case "rescore":
+			rescoreCmd(os.Args[2:])
+			return
+		case "watch":
+			watchCmd(os.Args[2:])
+			return
+		case "lsp":
+			lspCmd(os.Args[2:])
+			return
+		case "serve":
+			serveCmd(os.Args[2:])
+			return
+		case "annotate":
+			annotateCmd(os.Args[2:])
+			return
+		}
+	}
+
 	flag.Parse()
-	cfg := readCfg()
+	cfg := readCfg(flag.Args())
 	files := getFiles(flag.Args())
+	c := openCache()
 
-	measurers := make(chan *wmfp.MeasurerWMFP, len(files))
+	results := make(chan report.File, len(files))
 	for _, file := range files {
-		go measureFile(file, cfg, measurers)
+		go measureFile(c, file, cfg.For(file), results)
 	}
 
-	fmt.Println(combineMeasures(measurers, len(files)))
+	r := combineMeasures(results, len(files))
+	if *format == "html" {
+		if *output == "" {
+			log.Fatal("html format requires output directory, set it with -o")
+		}
+		die(site.Write(*output, r, cfg.For))
+	} else {
+		die(writeReport(r, cfg))
+	}
+	trimCache(c)
 }
 
-func measureFile(file string, config wmfp.Config, results chan<- *wmfp.MeasurerWMFP) {
-	measurer := wmfp.NewMeasurerWMFP(&config)
-	node, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ParseComments)
+// Writes report in format given by flag to output file or stdout
+func writeReport(r report.Report, cfg *config.Loaded) error {
+	if *output == "" {
+		return writeFormat(os.Stdout, r, cfg)
+	}
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	err = writeFormat(f, r, cfg)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func writeFormat(w io.Writer, r report.Report, cfg *config.Loaded) error {
+	if *format == "sarif" {
+		budgets := sarif.Budgets{Func: *funcBudget, File: *fileBudget, Package: *packageBudget}
+		return sarif.Write(w, r, cfg.For, budgets)
+	}
+	return report.Write(w, r, *format)
+}
+
+// Opens cache given by flags, returns nil if it is disabled
+func openCache() *cache.Cache {
+	if *noCache {
+		return nil
+	}
+	dir := *cacheDir
+	if dir == "" {
+		var err error
+		dir, err = cache.DefaultDir()
+		die(err)
+	}
+	c, err := cache.Open(dir, *cacheSize<<20)
+	die(err)
+	c.Log = func(err error) { log.Print(err) }
+	return c
+}
+
+// Removes least recently used cache entries, failures are only logged because
+// results are already measured
+func trimCache(c *cache.Cache) {
+	if c == nil {
+		return
+	}
+	if err := c.Trim(); err != nil {
+		c.Log(fmt.Errorf("cache: %w", err))
+	}
+}
+
+func measureFile(c *cache.Cache, file string, config wmfp.Config, results chan<- report.File) {
+	var result report.File
+	var err error
+	if c == nil {
+		result, err = report.MeasureFile(file, &config)
+	} else {
+		result, err = c.MeasureFile(file, &config)
+	}
 	die(err)
-	measurer.ParseFile(node)
-	results <- &measurer
+	results <- result
 }
 
-func combineMeasures(results <-chan *wmfp.MeasurerWMFP, mNum int) float64 {
-	var nextMeasurer *wmfp.MeasurerWMFP
-	var totalScore float64
+func combineMeasures(results <-chan report.File, mNum int) report.Report {
+	files := make([]report.File, 0, mNum)
 	for i := 0; i < mNum; i++ {
-		nextMeasurer = <-results
-		totalScore += nextMeasurer.Finish()
+		files = append(files, <-results)
 	}
-	return totalScore
+	return report.New(files)
 }
 
 func walkMatch(root, pattern string) ([]string, error) {
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Handles `gokys rescore`: recomputes saved json report under another config
+// without parsing sources
+func rescoreCmd(args []string) {
+	flags := flag.NewFlagSet("rescore", flag.ExitOnError)
+	path := flags.String("report", "", "Report saved with -format json")
+	cfgpath := flags.String("c", "", "Config file in xml, yaml, json or toml format (default is discovered)")
+	format := flags.String("format", "total", "Report format: total, text or json")
+	die(flags.Parse(args))
+	if *path == "" || flags.NArg() != 0 {
+		die(fmt.Errorf("usage: gokys rescore -report <report.json> [-c config] [-format format]"))
+	}
+
+	f, err := os.Open(*path)
+	die(err)
+	saved, err := report.ReadJSON(f)
+	f.Close()
+	die(err)
+
+	loaded, err := config.Resolve(*cfgpath, ".")
+	die(err)
+	rescored := report.Rescore(saved, func(file string) wmfp.Config { return loaded.For(file) })
+	die(report.Write(os.Stdout, rescored, *format))
+}
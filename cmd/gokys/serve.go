@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/server"
+)
+
+// Handles `gokys serve`: serves REST API for measuring uploaded sources
+func serveCmd(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := flags.String("addr", ":8080", "Address to listen on")
+	cfgpath := flags.String("c", "", "Default config file (default is discovered in current directory)")
+	configs := make(map[string]*config.Loaded)
+	flags.Func("config", "Named config available to requests as `name=path`, can be repeated", func(value string) error {
+		name, path, ok := strings.Cut(value, "=")
+		if !ok || name == "" {
+			return fmt.Errorf("config should be name=path, got %q", value)
+		}
+		loaded, err := config.LoadWithDefaults(path)
+		if err != nil {
+			return err
+		}
+		configs[name] = loaded
+		return nil
+	})
+	maxBytes := flags.Int64("max-bytes", server.DefaultOptions.MaxBytes>>20, "Limit of request body in megabytes")
+	maxUnpacked := flags.Int64("max-unpacked", server.DefaultOptions.MaxUnpacked>>20, "Limit of files unpacked from archive in megabytes")
+	maxFiles := flags.Int("max-files", server.DefaultOptions.MaxFiles, "Limit of number of files unpacked from archive")
+	timeout := flags.Duration("timeout", server.DefaultOptions.Timeout, "Limit of time spent on measuring single request")
+	die(flags.Parse(args))
+
+	if _, ok := configs[server.DefaultConfig]; !ok {
+		loaded, err := config.Resolve(*cfgpath, ".")
+		die(err)
+		configs[server.DefaultConfig] = loaded
+	}
+	handler := server.New(server.Options{
+		Configs:     configs,
+		MaxBytes:    *maxBytes << 20,
+		MaxUnpacked: *maxUnpacked << 20,
+		MaxFiles:    *maxFiles,
+		Timeout:     *timeout,
+	})
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       time.Minute,
+		// measuring is limited by timeout, the rest is left for writing response
+		WriteTimeout: *timeout + time.Minute,
+		IdleTimeout:  2 * time.Minute,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), *timeout)
+		defer cancel()
+		if err := srv.Shutdown(shutdown); err != nil {
+			log.Print(err)
+		}
+	}()
+	log.Printf("listening on %s", *addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		die(err)
+	}
+}
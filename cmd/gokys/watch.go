@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/watch"
+)
+
+// Handles `gokys watch`: measures files again whenever they change and
+// redraws summary of changes since start
+func watchCmd(args []string) {
+	flags := flag.NewFlagSet("watch", flag.ExitOnError)
+	cfgpath := flags.String("c", "", "Config file in xml, yaml, json or toml format (default is discovered)")
+	poll := flags.Duration("poll", 0, "Poll files with given interval instead of using file system notifications")
+	top := flags.Int("top", 10, "Number of functions with largest changes to show")
+	logpath := flags.String("log", "", "File to append summary of every change to as json lines")
+	die(flags.Parse(args))
+
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+	for i, root := range roots {
+		// `./...` is accepted for symmetry with go tools, directories are always watched recursively
+		if root = strings.TrimSuffix(root, "/..."); root == "..." {
+			root = "."
+		}
+		info, err := os.Stat(root)
+		die(err)
+		if !info.IsDir() {
+			die(fmt.Errorf("usage: gokys watch [flags] [dir|dir/...]..., %s is not a directory", root))
+		}
+		roots[i] = root
+	}
+
+	loaded, err := config.Resolve(*cfgpath, roots[0])
+	die(err)
+	c := openCache()
+	measure := func(path string) (report.File, error) {
+		cfg := loaded.For(path)
+		if c == nil {
+			return report.MeasureFile(path, &cfg)
+		}
+		return c.MeasureFile(path, &cfg)
+	}
+
+	var sessionLog *os.File
+	if *logpath != "" {
+		sessionLog, err = os.OpenFile(*logpath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		die(err)
+		defer sessionLog.Close()
+	}
+
+	files, err := watch.Files(roots)
+	die(err)
+	session, err := watch.NewSession(files, measure)
+	die(err)
+	draw(session.Summary(nil, *top), nil)
+	trimCache(c)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	err = watch.Watch(ctx, roots, *poll, func(changed []string) {
+		updateErr := session.Update(changed)
+		summary := session.Summary(changed, *top)
+		draw(summary, updateErr)
+		trimCache(c)
+		if sessionLog != nil {
+			if err := watch.WriteLog(sessionLog, summary); err != nil {
+				log.Print(err)
+			}
+		}
+	})
+	die(err)
+}
+
+// Prints summary, screen is cleared first when writing to terminal
+func draw(summary watch.Summary, err error) {
+	if isTerminal(os.Stdout) {
+		fmt.Print("\033[H\033[2J")
+	}
+	die(watch.WriteSummary(os.Stdout, summary))
+	if err != nil {
+		fmt.Println()
+		fmt.Println("error:", err)
+	}
+}
+
+// Reports whether file is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
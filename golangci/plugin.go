@@ -0,0 +1,59 @@
+// Package golangci registers gokys analyzer as golangci-lint module plugin.
+// It is a part of the main module, so golangci-lint builds it from a local
+// checkout without resolving go-kys separately.
+package golangci
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/bragov4ik/go-kys/pkg/analyzer"
+)
+
+func init() {
+	register.Plugin("gokys", New)
+}
+
+// Plugin for golangci-lint, its settings are decoded into `analyzer.Settings`
+type Plugin struct {
+	settings analyzer.Settings
+}
+
+// Creates plugin from settings of linter in golangci-lint config, settings
+// missing in config keep values of `analyzer.DefaultSettings`
+func New(settings any) (register.LinterPlugin, error) {
+	s := analyzer.DefaultSettings
+	if err := decodeSettings(settings, &s); err != nil {
+		return nil, err
+	}
+	return &Plugin{settings: s}, nil
+}
+
+// Decodes settings on top of `s` like `register.DecodeSettings`, so only
+// fields present in config are changed, even if they are set to zero
+func decodeSettings(settings any, s *analyzer.Settings) error {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return fmt.Errorf("encoding settings: %w", err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(s); err != nil {
+		return fmt.Errorf("decoding settings: %w", err)
+	}
+	return nil
+}
+
+// Returns gokys analyzer
+func (p *Plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{analyzer.New(p.settings)}, nil
+}
+
+// Analyzer uses only syntax, but facts of dependencies need type information
+func (p *Plugin) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
@@ -0,0 +1,40 @@
+package golangci
+
+import (
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/analyzer"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings any
+		want     analyzer.Settings
+	}{
+		{"Empty", nil, analyzer.DefaultSettings},
+		{"Budgets", map[string]any{"func-budget": 30, "package-budget": 600.5}, analyzer.Settings{Func: 30, Package: 600.5}},
+		{"FileBudget", map[string]any{"file-budget": 300}, analyzer.Settings{Func: analyzer.DefaultSettings.Func, File: 300}},
+		{"NoFuncBudget", map[string]any{"func-budget": 0}, analyzer.Settings{}},
+		{"Config", map[string]any{"config": ".gokys.yaml"}, analyzer.Settings{Config: ".gokys.yaml", Func: analyzer.DefaultSettings.Func}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(tt.settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := p.(*Plugin).settings; got != tt.want {
+				t.Errorf("settings = %+v, want %+v", got, tt.want)
+			}
+			analyzers, err := p.BuildAnalyzers()
+			if err != nil || len(analyzers) != 1 || analyzers[0].Name != "gokys" {
+				t.Errorf("BuildAnalyzers() = %v, %v, want gokys analyzer", analyzers, err)
+			}
+		})
+	}
+
+	if _, err := New(map[string]any{"func-budgte": 30}); err == nil {
+		t.Error("New() with unknown setting should fail")
+	}
+}
@@ -0,0 +1,133 @@
+// Package analyzer exposes WMFP metric as go/analysis analyzer, which reports
+// functions, files and packages above effort budgets.
+package analyzer
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"reflect"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Settings of analyzer, budgets are in minutes and 0 disables a budget
+type Settings struct {
+	// Config file, discovered from directory of package if empty
+	Config string `json:"config"`
+	// Budget of every function
+	Func float64 `json:"func-budget"`
+	// Budget of every file
+	File float64 `json:"file-budget"`
+	// Budget of whole package
+	Package float64 `json:"package-budget"`
+}
+
+// Settings used by `Analyzer`
+var DefaultSettings = Settings{Func: 120}
+
+// Analyzer with default settings, which can be changed by its flags
+var Analyzer = New(DefaultSettings)
+
+// Effort of package exported as analysis fact
+type Effort struct {
+	// WMFP score of package in minutes
+	Score float64
+	// Minutes removed from score by `//gokys:` directives
+	Suppressed float64
+	// Raw counts behind score
+	Counts wmfp.Counts
+	// Score of package and all packages it depends on which were analyzed
+	Transitive float64
+}
+
+// Marks `Effort` as fact
+func (*Effort) AFact() {}
+
+func (e *Effort) String() string {
+	return fmt.Sprintf("effort %.2f, transitive %.2f", e.Score, e.Transitive)
+}
+
+// Creates analyzer with given settings, they can be changed by its flags. Its
+// result is report of analyzed package
+func New(settings Settings) *analysis.Analyzer {
+	s := settings
+	a := &analysis.Analyzer{
+		Name: "gokys",
+		Doc: "reports functions, files and packages whose WMFP effort estimate is above budget\n\n" +
+			"Effort is measured in minutes with weights of gokys config file.",
+		Run:        func(pass *analysis.Pass) (interface{}, error) { return run(pass, &s) },
+		FactTypes:  []analysis.Fact{new(Effort)},
+		ResultType: reflect.TypeOf((*report.Report)(nil)),
+	}
+	a.Flags.Init(a.Name, flag.ExitOnError)
+	a.Flags.StringVar(&s.Config, "config", s.Config, "config file in xml, yaml, json or toml format (default is discovered)")
+	a.Flags.Float64Var(&s.Func, "func-budget", s.Func, "budget of every function in minutes, 0 disables it")
+	a.Flags.Float64Var(&s.File, "file-budget", s.File, "budget of every file in minutes, 0 disables it")
+	a.Flags.Float64Var(&s.Package, "package-budget", s.Package, "budget of every package in minutes, 0 disables it")
+	return a
+}
+
+func run(pass *analysis.Pass, s *Settings) (interface{}, error) {
+	var files []report.File
+	if len(pass.Files) > 0 {
+		dir := filepath.Dir(pass.Fset.File(pass.Files[0].Pos()).Name())
+		loaded, err := config.Resolve(s.Config, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range pass.Files {
+			name := pass.Fset.File(file.Pos()).Name()
+			cfg := loaded.For(name)
+			result, err := report.Measure(pass.Fset, name, file, &cfg)
+			if err != nil {
+				return nil, err
+			}
+			checkFile(pass, file, &result, s)
+			files = append(files, result)
+		}
+	}
+
+	r := report.New(files)
+	if s.Package > 0 && r.Score > s.Package {
+		pass.Reportf(pass.Files[0].Name.Pos(), "package %s takes %.2f minutes, budget is %g",
+			pass.Pkg.Name(), r.Score, s.Package)
+	}
+
+	effort := &Effort{Score: r.Score, Suppressed: r.Suppressed, Counts: r.Counts, Transitive: r.Score}
+	for _, fact := range pass.AllPackageFacts() {
+		if dep, ok := fact.Fact.(*Effort); ok && fact.Package != pass.Pkg {
+			effort.Transitive += dep.Score
+		}
+	}
+	pass.ExportPackageFact(effort)
+	return &r, nil
+}
+
+// Reports file and its functions above budgets
+func checkFile(pass *analysis.Pass, file *ast.File, result *report.File, s *Settings) {
+	if s.File > 0 && result.Score > s.File {
+		pass.Reportf(file.Name.Pos(), "file %s takes %.2f minutes, budget is %g",
+			filepath.Base(result.Path), result.Score, s.File)
+	}
+	if s.Func <= 0 {
+		return
+	}
+	// functions of report are in order of declarations
+	i := 0
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		if fn := result.Funcs[i]; fn.Score > s.Func {
+			pass.Reportf(fd.Name.Pos(), "func %s takes %.2f minutes, budget is %g", fn.Name, fn.Score, s.Func)
+		}
+		i++
+	}
+}
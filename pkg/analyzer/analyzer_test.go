@@ -0,0 +1,49 @@
+package analyzer
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/bragov4ik/go-kys/pkg/report"
+)
+
+func TestAnalyzer(t *testing.T) {
+	testdata := analysistest.TestData()
+	cfg := filepath.Join(testdata, "config.yaml")
+
+	tests := []struct {
+		name     string
+		settings Settings
+		pkg      string
+	}{
+		{"Func", Settings{Config: cfg, Func: 250}, "a"},
+		{"FileAndPackage", Settings{Config: cfg, File: 1, Package: 1}, "b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := analysistest.Run(t, testdata, New(tt.settings), tt.pkg)
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			r := results[0].Result.(*report.Report)
+			if r.Score <= 0 {
+				t.Errorf("result = %+v, should have score", r)
+			}
+
+			// facts of dependencies are available as well
+			efforts := make(map[string]*Effort)
+			for _, fact := range results[0].Pass.AllPackageFacts() {
+				efforts[fact.Package.Path()] = fact.Fact.(*Effort)
+			}
+			var transitive float64
+			for _, effort := range efforts {
+				transitive += effort.Score
+			}
+			if own := efforts[tt.pkg]; own == nil || own.Score != r.Score || own.Transitive != transitive {
+				t.Errorf("facts = %v, want effort %v with transitive %v", efforts, r.Score, transitive)
+			}
+		})
+	}
+}
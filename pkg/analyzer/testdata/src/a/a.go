@@ -0,0 +1,33 @@
+package a // want package:"effort [0-9.]+, transitive [0-9.]+"
+
+func small() {}
+
+func big(x int) int { // want "func big takes [0-9.]+ minutes, budget is 250"
+	if x > 1 {
+		return 1
+	}
+	if x > 2 {
+		return 2
+	}
+	if x > 3 {
+		return 3
+	}
+	return 0
+}
+
+//gokys:ignore
+func ignored(x int) int {
+	if x > 1 {
+		return 1
+	}
+	if x > 2 {
+		return 2
+	}
+	if x > 3 {
+		return 3
+	}
+	return 0
+}
+
+// Exported function
+func A() int { return big(1) + ignored(1) }
@@ -0,0 +1,6 @@
+package b // want package:"effort [0-9.]+, transitive [0-9.]+" "file b.go takes [0-9.]+ minutes, budget is 1" "package b takes [0-9.]+ minutes, budget is 1"
+
+import "a"
+
+// Calls a
+func B() int { return a.A() }
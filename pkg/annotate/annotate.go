@@ -0,0 +1,149 @@
+// Package annotate attributes WMFP counts of a file to its source lines.
+package annotate
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/bragov4ik/go-kys/pkg/assets"
+	"github.com/bragov4ik/go-kys/pkg/doccov"
+	"github.com/bragov4ik/go-kys/pkg/pragma"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Counts of a single source line
+type Line struct {
+	// Number of line starting from 1
+	Number int `json:"line"`
+	// Text of line without line break
+	Text string `json:"text"`
+	// Counts of nodes on line weighted by directives
+	Counts wmfp.Counts `json:"counts"`
+	// Minutes of counts
+	Minutes float64 `json:"minutes"`
+}
+
+// Source file with counts of every line
+type File struct {
+	// Path to file
+	Path string `json:"path"`
+	// Sum of minutes of lines, it equals score of file
+	Score float64 `json:"score"`
+	// Largest minutes of a single line
+	Max float64 `json:"max"`
+	// All lines of file
+	Lines []Line `json:"lines"`
+}
+
+// Parses and annotates file at given path
+func AnnotateFile(path string, cfg *wmfp.Config) (File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return File{}, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return File{}, err
+	}
+	return Annotate(fset, path, src, file, cfg)
+}
+
+// Annotates already parsed file with source `src`. Counts of every node are
+// attributed to line of its main token (e.g. operator of binary expression),
+// comments and string literals spanning several lines are split between them
+// evenly. Embedded files are attributed to lines of their `//go:embed`
+// directives
+func Annotate(fset *token.FileSet, path string, src []byte, file *ast.File, cfg *wmfp.Config) (File, error) {
+	directives, err := pragma.Parse(fset, file)
+	if err != nil {
+		return File{}, err
+	}
+	measurer := wmfp.NewMeasurerWMFP(cfg)
+	measurer.Directives = directives
+	measurer.DocCov.TestFile = doccov.IsTestFile(path)
+	measurer.Attribute = true
+	measurer.ParseFile(file)
+
+	result := File{Path: path, Lines: splitLines(src)}
+	add := func(line int, counts wmfp.Counts) {
+		if line >= 1 && line <= len(result.Lines) {
+			result.Lines[line-1].Counts = result.Lines[line-1].Counts.Add(counts)
+		}
+	}
+	for _, c := range measurer.Contributions() {
+		first := fset.Position(position(c.Node)).Line
+		last := first
+		switch c.Node.(type) {
+		case *ast.Comment, *ast.BasicLit:
+			last = fset.Position(c.Node.End() - 1).Line
+		}
+		part := c.Counts.Scale(1 / float64(last-first+1))
+		for line := first; line <= last; line++ {
+			add(line, part)
+		}
+	}
+
+	embedded, err := assets.Measure(file, filepath.Dir(path), &cfg.Assets)
+	if err != nil {
+		return File{}, err
+	}
+	for _, asset := range embedded {
+		counts := wmfp.Counts{Assets: assets.Count([]assets.Asset{asset})}
+		add(embedLine(fset, file, asset.Pattern), counts.Scale(directives.File()))
+	}
+
+	for i := range result.Lines {
+		line := &result.Lines[i]
+		line.Minutes = line.Counts.Score(cfg)
+		result.Score += line.Minutes
+		if line.Minutes > result.Max {
+			result.Max = line.Minutes
+		}
+	}
+	return result, nil
+}
+
+// Returns position of token which counts of node are attributed to
+func position(n ast.Node) token.Pos {
+	switch v := n.(type) {
+	case *ast.BinaryExpr:
+		return v.OpPos
+	case *ast.AssignStmt:
+		return v.TokPos
+	case *ast.IncDecStmt:
+		return v.TokPos
+	case *ast.KeyValueExpr:
+		return v.Colon
+	case *ast.File:
+		return v.Package
+	}
+	return n.Pos()
+}
+
+// Returns line of first `//go:embed` directive with pattern, 0 if there is none
+func embedLine(fset *token.FileSet, file *ast.File, pattern string) int {
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			if slices.Contains(assets.DirectivePatterns(comment), pattern) {
+				return fset.Position(comment.Pos()).Line
+			}
+		}
+	}
+	return 0
+}
+
+// Splits source into numbered lines without counts
+func splitLines(src []byte) []Line {
+	texts := bytes.Split(bytes.TrimSuffix(src, []byte("\n")), []byte("\n"))
+	lines := make([]Line, len(texts))
+	for i, text := range texts {
+		lines[i] = Line{Number: i + 1, Text: string(bytes.TrimSuffix(text, []byte("\r")))}
+	}
+	return lines
+}
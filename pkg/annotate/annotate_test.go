@@ -0,0 +1,127 @@
+package annotate
+
+import (
+	"go/parser"
+	"go/token"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/arithmetic"
+	"github.com/bragov4ik/go-kys/pkg/assets"
+	"github.com/bragov4ik/go-kys/pkg/comments"
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+const src = `package p
+
+import _ "embed"
+
+//go:embed *.sql
+var query string
+
+/* Counts positive
+numbers */
+func f(xs []int) (n int) {
+	for _, x := range xs {
+		if x > 0 &&
+			x < 10 {
+			n++
+		}
+	}
+	return
+}
+
+//gokys:ignore
+func g(x int) int { return x * 2 }
+`
+
+func TestAnnotateFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a.sql"), []byte("SELECT 1;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := wmfp.Config{
+		CycloComp:      cyclo.Weights{If: 2, Rng: 1, And: 1},
+		Comment:        comments.Weights{Word: 1},
+		ArithmeticComp: arithmetic.Weights{Mul: 1, Inc: 1},
+		Halstead:       0.1,
+		Assets:         assets.Weights{SQL: assets.Kind{Byte: 1}},
+	}
+
+	file, err := AnnotateFile(path, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := report.MeasureFile(path, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(file.Score-want.Score) > 1e-9 {
+		t.Errorf("Score = %v, want score of file %v", file.Score, want.Score)
+	}
+	if len(file.Lines) != 21 || file.Lines[10].Text != "\tfor _, x := range xs {" {
+		t.Fatalf("got %d lines, line 11 is %q", len(file.Lines), file.Lines[10].Text)
+	}
+
+	tests := []struct {
+		line  int
+		name  string
+		count float64
+	}{
+		{5, "embed.sql.byte", 10},
+		{8, "comment.word", 2.5},
+		{9, "comment.word", 2.5},
+		{11, "cyclomatic.rng", 1},
+		{12, "cyclomatic.if", 1},
+		{12, "cyclomatic.and", 1},
+		{13, "cyclomatic.and", 0},
+		{14, "arithmetic.inc", 1},
+		{21, "arithmetic.mul", 0},
+	}
+	for _, tt := range tests {
+		line := file.Lines[tt.line-1]
+		var count float64
+		for _, term := range line.Counts.Terms(&cfg) {
+			if term.Name == tt.name {
+				count = term.Count
+			}
+		}
+		if count != tt.count {
+			t.Errorf("line %d: %s = %v, want %v", tt.line, tt.name, count, tt.count)
+		}
+	}
+	if file.Max != file.Lines[4].Minutes {
+		t.Errorf("Max = %v, want minutes of embed directive %v", file.Max, file.Lines[4].Minutes)
+	}
+}
+
+func TestEmbedLine(t *testing.T) {
+	src := `package p
+
+import _ "embed"
+
+//go:embed data.txt
+var data string
+
+//go:embed a "b c"
+var a string
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "p.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for pattern, want := range map[string]int{"data.txt": 5, "a": 8, "b c": 8, "b": 0, "x": 0} {
+		if got := embedLine(fset, file, pattern); got != want {
+			t.Errorf("embedLine(%q) = %v, want %v", pattern, got, want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package annotate
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Column of metric minutes in annotated source
+type Column struct {
+	// Short name shown in header
+	Short string
+	// Name of metric, the same as in config
+	Metric string
+}
+
+// Columns shown with `Options.Metrics`
+var Columns = []Column{
+	{"cyc", "cyclomatic"},
+	{"hal", "halstead"},
+	{"arith", "arithmetic"},
+	{"inl", "inline"},
+	{"cmt", "comment"},
+	{"struct", "codestruct"},
+	{"nest", "nesting"},
+	{"doc", "doccov"},
+	{"embed", "embed"},
+}
+
+// Share of hottest line minutes from which lines are coloured
+const (
+	Warm = 0.2
+	Hot  = 0.5
+)
+
+// ANSI escape codes
+const (
+	red    = "\033[31m"
+	yellow = "\033[33m"
+	dim    = "\033[2m"
+	reset  = "\033[0m"
+)
+
+// Options of `Write`
+type Options struct {
+	// Show minutes of every metric in separate columns
+	Metrics bool
+	// Colour lines by their minutes with ANSI escape codes
+	Color bool
+}
+
+// Writes source of file with minutes of every line in gutter, like
+// `git blame`. Lines are followed by total of file
+func Write(w io.Writer, f File, cfg *wmfp.Config, opts Options) error {
+	digits := len(fmt.Sprint(len(f.Lines)))
+	if opts.Metrics {
+		header := fmt.Sprintf("%7s", "min")
+		for _, c := range Columns {
+			header += fmt.Sprintf(" %6s", c.Short)
+		}
+		if _, err := fmt.Fprintf(w, "%s %*s │\n", header, digits, ""); err != nil {
+			return err
+		}
+	}
+	for _, line := range f.Lines {
+		gutter := cell(line.Minutes, 7)
+		if opts.Metrics {
+			breakdown := line.Counts.Breakdown(cfg)
+			minutes := make(map[string]float64)
+			for _, part := range breakdown.Parts() {
+				minutes[part.Name] = part.Minutes
+			}
+			for _, c := range Columns {
+				gutter += " " + cell(minutes[c.Metric], 6)
+			}
+		}
+		gutter += fmt.Sprintf(" %*d │ ", digits, line.Number)
+
+		var err error
+		switch color := heat(line.Minutes, f.Max); {
+		case !opts.Color:
+			_, err = fmt.Fprintln(w, gutter+line.Text)
+		case color == "":
+			_, err = fmt.Fprintln(w, dim+gutter+reset+line.Text)
+		default:
+			_, err = fmt.Fprintln(w, color+gutter+line.Text+reset)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%.2f minutes in %s\n", f.Score, f.Path)
+	return err
+}
+
+// Formats minutes for gutter column of given width, zero is left blank
+func cell(minutes float64, width int) string {
+	if minutes == 0 {
+		return strings.Repeat(" ", width)
+	}
+	return fmt.Sprintf("%*.2f", width, minutes)
+}
+
+// Returns colour of line by share of hottest line, empty for cold lines
+func heat(minutes, max float64) string {
+	switch {
+	case max == 0:
+		return ""
+	case minutes >= Hot*max:
+		return red
+	case minutes >= Warm*max:
+		return yellow
+	}
+	return ""
+}
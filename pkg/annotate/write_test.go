@@ -0,0 +1,66 @@
+package annotate
+
+import (
+	"strings"
+	"testing"
+
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+func TestWrite(t *testing.T) {
+	cfg := wmfp.Config{CycloComp: cyclo.Weights{If: 2}, Halstead: 0.1}
+	file := File{
+		Path:  "p.go",
+		Score: 2.5,
+		Max:   2,
+		Lines: []Line{
+			{Number: 1, Text: "package p", Counts: wmfp.Counts{Halstead: 5}, Minutes: 0.5},
+			{Number: 2, Text: ""},
+			{Number: 3, Text: "if x {", Counts: wmfp.Counts{CycloComp: cyclo.Counts{If: 1}}, Minutes: 2},
+		},
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{
+			"plain",
+			Options{},
+			"   0.50 1 │ package p\n" +
+				"        2 │ \n" +
+				"   2.00 3 │ if x {\n" +
+				"2.50 minutes in p.go\n",
+		},
+		{
+			"metrics",
+			Options{Metrics: true},
+			"    min    cyc    hal  arith    inl    cmt struct   nest    doc  embed   │\n" +
+				"   0.50          0.50" + strings.Repeat(" ", 49) + " 1 │ package p\n" +
+				strings.Repeat(" ", 70) + " 2 │ \n" +
+				"   2.00   2.00" + strings.Repeat(" ", 56) + " 3 │ if x {\n" +
+				"2.50 minutes in p.go\n",
+		},
+		{
+			"color",
+			Options{Color: true},
+			yellow + "   0.50 1 │ package p" + reset + "\n" +
+				dim + "        2 │ " + reset + "\n" +
+				red + "   2.00 3 │ if x {" + reset + "\n" +
+				"2.50 minutes in p.go\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := Write(&b, file, &cfg, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("Write() =\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
@@ -10,111 +10,147 @@ import (
 // Weights is structure with weights for arithmetic metric calculator
 type Weights struct {
 	// Weight for addition
-	Add float64 `xml:"add"`
+	Add float64 `xml:"add" yaml:"add" json:"add" toml:"add"`
 	// Weight for subtraction
-	Sub float64 `xml:"sub"`
+	Sub float64 `xml:"sub" yaml:"sub" json:"sub" toml:"sub"`
 	// Weight for multiplication
-	Mul float64 `xml:"mul"`
+	Mul float64 `xml:"mul" yaml:"mul" json:"mul" toml:"mul"`
 	// Weight for division
-	Quo float64 `xml:"quo"`
+	Quo float64 `xml:"quo" yaml:"quo" json:"quo" toml:"quo"`
 	// Weight for remainder of division
-	Rem float64 `xml:"rem"`
+	Rem float64 `xml:"rem" yaml:"rem" json:"rem" toml:"rem"`
 	// Weight for addition assigned
-	AddAssign float64 `xml:"add_assign"`
+	AddAssign float64 `xml:"add_assign" yaml:"add_assign" json:"add_assign" toml:"add_assign"`
 	// Weight for subtraction assigned
-	SubAssign float64 `xml:"sub_assign"`
+	SubAssign float64 `xml:"sub_assign" yaml:"sub_assign" json:"sub_assign" toml:"sub_assign"`
 	// Weight for multiplication assigned
-	MulAssign float64 `xml:"mul_assign"`
+	MulAssign float64 `xml:"mul_assign" yaml:"mul_assign" json:"mul_assign" toml:"mul_assign"`
 	// Weight for division assigned
-	QuoAssign float64 `xml:"quo_assign"`
+	QuoAssign float64 `xml:"quo_assign" yaml:"quo_assign" json:"quo_assign" toml:"quo_assign"`
 	// Weight for remainder assigned
-	RemAssign float64 `xml:"rem_assign"`
+	RemAssign float64 `xml:"rem_assign" yaml:"rem_assign" json:"rem_assign" toml:"rem_assign"`
 	// Weight for increment by 1
-	Inc float64 `xml:"inc"`
+	Inc float64 `xml:"inc" yaml:"inc" json:"inc" toml:"inc"`
 	// Weight for decrement by 1
-	Dec float64 `xml:"dec"`
+	Dec float64 `xml:"dec" yaml:"dec" json:"dec" toml:"dec"`
+}
+
+// Counts is structure with raw numbers of arithmetic operations, fields are
+// named after weights
+type Counts struct {
+	// Number of additions
+	Add float64 `json:"add"`
+	// Number of subtractions
+	Sub float64 `json:"sub"`
+	// Number of multiplications
+	Mul float64 `json:"mul"`
+	// Number of divisions
+	Quo float64 `json:"quo"`
+	// Number of remainders of division
+	Rem float64 `json:"rem"`
+	// Number of additions assigned
+	AddAssign float64 `json:"add_assign"`
+	// Number of subtractions assigned
+	SubAssign float64 `json:"sub_assign"`
+	// Number of multiplications assigned
+	MulAssign float64 `json:"mul_assign"`
+	// Number of divisions assigned
+	QuoAssign float64 `json:"quo_assign"`
+	// Number of remainders assigned
+	RemAssign float64 `json:"rem_assign"`
+	// Number of increments by 1
+	Inc float64 `json:"inc"`
+	// Number of decrements by 1
+	Dec float64 `json:"dec"`
+}
+
+// Computes weighted result from counts
+func (c Counts) Score(config *Weights) float64 {
+	return c.Add*config.Add + c.Sub*config.Sub + c.Mul*config.Mul + c.Quo*config.Quo + c.Rem*config.Rem +
+		c.AddAssign*config.AddAssign + c.SubAssign*config.SubAssign + c.MulAssign*config.MulAssign +
+		c.QuoAssign*config.QuoAssign + c.RemAssign*config.RemAssign + c.Inc*config.Inc + c.Dec*config.Dec
 }
 
 // Metric is the temporal state for calculations of metrics
 type Metric struct {
 	// Config with weights
 	Config Weights
-	comp   float64
+	counts Counts
 }
 
 // Parses node from ast
-func (m *Metric) ParseNode(n ast.Node) { m.comp += getArithmeticComp(&n, &m.Config) }
+func (m *Metric) ParseNode(n ast.Node) { countArithmetic(n, &m.counts) }
+
+// Parses node like `ParseNode`, but returns counts of node instead of
+// collecting them
+func (m *Metric) Count(n ast.Node) (c Counts) {
+	countArithmetic(n, &c)
+	return
+}
 
 // Finishes calculation and returns result
-func (m Metric) Finish() float64 { return m.comp }
+func (m Metric) Finish() float64 { return m.counts.Score(&m.Config) }
+
+// Returns raw counts collected so far
+func (m Metric) Counts() Counts { return m.counts }
 
-func getArithmeticComp(n *ast.Node, config *Weights) float64 {
-	var comp float64
-	switch v := (*n).(type) {
+func countArithmetic(n ast.Node, c *Counts) {
+	switch v := n.(type) {
 	case *ast.BinaryExpr:
-		comp = getBinaryComp(v, config)
+		countBinary(v, c)
 	case *ast.UnaryExpr:
-		comp = getUnaryComp(v, config)
+		countUnary(v, c)
 	case *ast.IncDecStmt:
-		comp = getIncDecComp(v, config)
+		countIncDec(v, c)
 	case *ast.AssignStmt:
-		comp = getAssignComp(v, config)
+		countAssign(v, c)
 	}
-	return comp
 }
 
-func getBinaryComp(n *ast.BinaryExpr, config *Weights) float64 {
-	var comp float64
+func countBinary(n *ast.BinaryExpr, c *Counts) {
 	switch n.Op {
 	case token.ADD:
-		comp = config.Add
+		c.Add++
 	case token.SUB:
-		comp = config.Sub
+		c.Sub++
 	case token.MUL:
-		comp = config.Mul
+		c.Mul++
 	case token.QUO:
-		comp = config.Quo
+		c.Quo++
 	case token.REM:
-		comp = config.Rem
+		c.Rem++
 	}
-	return comp
 }
 
-func getUnaryComp(n *ast.UnaryExpr, config *Weights) float64 {
-	var comp float64
+func countUnary(n *ast.UnaryExpr, c *Counts) {
 	switch n.Op {
 	case token.ADD:
-		comp = config.Add
+		c.Add++
 	case token.SUB:
-		comp = config.Sub
+		c.Sub++
 	}
-	return comp
 }
 
-func getIncDecComp(n *ast.IncDecStmt, config *Weights) float64 {
-	var comp float64
+func countIncDec(n *ast.IncDecStmt, c *Counts) {
 	switch n.Tok {
 	case token.INC:
-		comp = config.Inc
+		c.Inc++
 	case token.DEC:
-		comp = config.Dec
+		c.Dec++
 	}
-	return comp
 }
 
-func getAssignComp(n *ast.AssignStmt, config *Weights) float64 {
-	var comp float64
+func countAssign(n *ast.AssignStmt, c *Counts) {
 	switch n.Tok {
 	case token.ADD_ASSIGN:
-		comp = config.AddAssign
+		c.AddAssign++
 	case token.SUB_ASSIGN:
-		comp = config.SubAssign
+		c.SubAssign++
 	case token.MUL_ASSIGN:
-		comp = config.MulAssign
+		c.MulAssign++
 	case token.QUO_ASSIGN:
-		comp = config.QuoAssign
+		c.QuoAssign++
 	case token.REM_ASSIGN:
-		comp = config.RemAssign
+		c.RemAssign++
 	}
-	return comp
 }
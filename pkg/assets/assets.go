@@ -0,0 +1,266 @@
+// Package with metric of data files embedded into binary using `//go:embed`
+// directives.
+package assets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go/ast"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Weights of a single kind of embedded files
+type Kind struct {
+	// Weight of every byte
+	Byte float64 `xml:"byte" yaml:"byte" json:"byte" toml:"byte"`
+	// Weight of every line
+	Line float64 `xml:"line" yaml:"line" json:"line" toml:"line"`
+}
+
+// Weights for metric
+type Weights struct {
+	// Weights of files which do not have special kind
+	Kind `yaml:",inline"`
+	// Weights of templates (`.tmpl`, `.tpl`, `.gotmpl`, `.gohtml`)
+	Template Kind `xml:"template" yaml:"template" json:"template" toml:"template"`
+	// Weights of SQL files (`.sql`)
+	SQL Kind `xml:"sql" yaml:"sql" json:"sql" toml:"sql"`
+	// Weights of JSON files (`.json`)
+	JSON Kind `xml:"json" yaml:"json" json:"json" toml:"json"`
+}
+
+// Bytes and lines of embedded files by kind, it has the same layout as
+// weights, so every count is multiplied by weight with the same path
+type Counts Weights
+
+// Computes weighted score from counts
+func (c Counts) Score(w *Weights) float64 {
+	return c.Kind.score(&w.Kind) + c.Template.score(&w.Template) + c.SQL.score(&w.SQL) + c.JSON.score(&w.JSON)
+}
+
+func (k Kind) score(w *Kind) float64 { return k.Byte*w.Byte + k.Line*w.Line }
+
+// Single embedded file
+type Asset struct {
+	// Directive pattern which matched file
+	Pattern string `json:"pattern"`
+	// Path to file
+	Path string `json:"path"`
+	// Size of file in bytes
+	Bytes uint `json:"bytes"`
+	// Number of lines in file
+	Lines uint `json:"lines"`
+	// Score of file
+	Score float64 `json:"score"`
+}
+
+// Returns weights for embedded file depending on its extension
+func (w *Weights) For(name string) Kind { return *w.kindOf(name) }
+
+func (w *Weights) kindOf(name string) *Kind {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tmpl", ".tpl", ".gotmpl", ".gohtml":
+		return &w.Template
+	case ".sql":
+		return &w.SQL
+	case ".json":
+		return &w.JSON
+	}
+	return &w.Kind
+}
+
+// Finds and measures files embedded by directives of parsed file, patterns
+// are resolved relative to directory `dir` of the file
+func Measure(file *ast.File, dir string, config *Weights) ([]Asset, error) {
+	return MeasureContext(context.Background(), file, dir, config)
+}
+
+// Like `Measure`, but stops resolving patterns when context is done
+func MeasureContext(ctx context.Context, file *ast.File, dir string, config *Weights) ([]Asset, error) {
+	assets := []Asset{}
+	seen := make(map[string]bool)
+	for _, pattern := range Patterns(file) {
+		paths, err := ResolveContext(ctx, dir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return nil, err
+			}
+			asset := Asset{Pattern: pattern, Path: p, Bytes: uint(len(data)), Lines: countLines(data)}
+			asset.Score = asset.Counts().Score(config)
+			assets = append(assets, asset)
+		}
+	}
+	return assets, nil
+}
+
+// Sums bytes and lines of assets by their kinds
+func Count(assets []Asset) Counts {
+	var c Counts
+	for _, asset := range assets {
+		kind := (*Weights)(&c).kindOf(asset.Path)
+		kind.Byte += float64(asset.Bytes)
+		kind.Line += float64(asset.Lines)
+	}
+	return c
+}
+
+// Returns bytes and lines of asset under its kind
+func (a Asset) Counts() Counts { return Count([]Asset{a}) }
+
+// Sums scores of assets
+func Total(assets []Asset) (total float64) {
+	for _, asset := range assets {
+		total += asset.Score
+	}
+	return
+}
+
+// Returns patterns of all `//go:embed` directives in file
+func Patterns(file *ast.File) []string {
+	var patterns []string
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			patterns = append(patterns, DirectivePatterns(comment)...)
+		}
+	}
+	return patterns
+}
+
+// Returns patterns of comment if it is a `//go:embed` directive
+func DirectivePatterns(comment *ast.Comment) []string {
+	args := strings.TrimPrefix(comment.Text, "//go:embed")
+	if args == comment.Text || (args != "" && args[0] != ' ' && args[0] != '\t') {
+		return nil
+	}
+	return splitArgs(args)
+}
+
+// Splits directive arguments, which may be quoted as go strings
+func splitArgs(args string) []string {
+	var result []string
+	for {
+		args = strings.TrimLeft(args, " \t")
+		if args == "" {
+			return result
+		}
+
+		end := strings.IndexAny(args, " \t")
+		if args[0] == '"' || args[0] == '`' {
+			if quoted, err := strconv.QuotedPrefix(args); err == nil {
+				unquoted, _ := strconv.Unquote(quoted)
+				result = append(result, unquoted)
+				args = args[len(quoted):]
+				continue
+			}
+		}
+		if end < 0 {
+			return append(result, args)
+		}
+		result = append(result, args[:end])
+		args = args[end:]
+	}
+}
+
+// Returns files matched by pattern the same way `go build` does: directories
+// are embedded recursively excluding files starting with `.` or `_` unless
+// pattern has `all:` prefix. Patterns with `.` or `..` elements or leading `/`
+// and matches outside of `dir` (e.g. through symlinks) are rejected
+func Resolve(dir, pattern string) ([]string, error) {
+	return ResolveContext(context.Background(), dir, pattern)
+}
+
+// Like `Resolve`, but stops walking directories when context is done
+func ResolveContext(ctx context.Context, dir, pattern string) ([]string, error) {
+	all := strings.HasPrefix(pattern, "all:")
+	pattern = strings.TrimPrefix(pattern, "all:")
+	if !validPattern(pattern) {
+		return nil, fmt.Errorf("invalid embed pattern %q", pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid embed pattern %q: %w", pattern, err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(pattern)))
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	root, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, match := range matches {
+		if err := inside(root, match); err != nil {
+			return nil, fmt.Errorf("embed pattern %q: %w", pattern, err)
+		}
+		err := filepath.WalkDir(match, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if p != match && !all && (strings.HasPrefix(d.Name(), ".") || strings.HasPrefix(d.Name(), "_")) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.Type().IsRegular() {
+				files = append(files, p)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Reports whether pattern is accepted by `go build`: slash separated path
+// without empty, `.` or `..` elements
+func validPattern(pattern string) bool {
+	return pattern != "." && fs.ValidPath(pattern) && !strings.Contains(pattern, `\`)
+}
+
+// Returns error if path resolves outside of directory `root`, which should
+// have symlinks evaluated
+func inside(root, p string) error {
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(root, real)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%s is outside of package directory", p)
+	}
+	return nil
+}
+
+func countLines(data []byte) uint {
+	lines := uint(bytes.Count(data, []byte{'\n'}))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		lines++
+	}
+	return lines
+}
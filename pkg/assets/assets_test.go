@@ -0,0 +1,131 @@
+package assets
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	src := "package main\n" +
+		"import \"embed\"\n" +
+		"//go:embed a.txt \"b c.txt\" `d/*.sql`\n" +
+		"var f embed.FS\n" +
+		"//go:embedded not a directive\n" +
+		"// go:embed not a directive either\n" +
+		"//go:embed all:static\n" +
+		"var s embed.FS\n"
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a.txt", "b c.txt", "d/*.sql", "all:static"}
+	if got := Patterns(file); !reflect.DeepEqual(got, want) {
+		t.Errorf("Patterns() = %q, want %q", got, want)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.txt":               "one\ntwo\n",
+		"queries/get.sql":     "SELECT 1",
+		"static/index.tmpl":   "{{.}}\n",
+		"static/_hidden.json": "{}",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	src := "package main\n" +
+		"//go:embed a.txt queries/*.sql\n" +
+		"var a string\n" +
+		"//go:embed static a.txt\n" +
+		"var b string\n"
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Weights{
+		Kind:     Kind{Byte: 1, Line: 100},
+		SQL:      Kind{Byte: 2},
+		Template: Kind{Line: 1000},
+		JSON:     Kind{Byte: 10000},
+	}
+	got, err := Measure(file, dir, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Asset{
+		{"a.txt", filepath.Join(dir, "a.txt"), 8, 2, 208},
+		{"queries/*.sql", filepath.Join(dir, "queries", "get.sql"), 8, 1, 16},
+		{"static", filepath.Join(dir, "static", "index.tmpl"), 6, 1, 1000},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Measure() = %+v, want %+v", got, want)
+	}
+	if total := Total(got); total != 1224 {
+		t.Errorf("Total() = %v, want 1224", total)
+	}
+
+	file, err = parser.ParseFile(token.NewFileSet(), "", "package main\n//go:embed all:static\nvar c string\n", parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Measure(file, dir, &cfg); err != nil || len(got) != 2 {
+		t.Errorf("Measure(all:static) = %+v, %v, want 2 assets", got, err)
+	}
+}
+
+func TestResolveRejects(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "pkg")
+	if err := os.MkdirAll(filepath.Join(dir, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"secret.txt", "pkg/data/a.txt"} {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(root, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []string{
+		"../secret.txt",
+		"../*",
+		"../../../../../../../etc/passwd",
+		"/etc/passwd",
+		"data/../../secret.txt",
+		"./data",
+		".",
+		"..",
+		"data//a.txt",
+		"data/",
+		`data\a.txt`,
+		"all:../secret.txt",
+		"link/secret.txt",
+		"link/*",
+	}
+	for _, pattern := range tests {
+		if files, err := Resolve(dir, pattern); err == nil {
+			t.Errorf("Resolve(%q) = %q, want error", pattern, files)
+		}
+	}
+	if files, err := Resolve(dir, "data/*.txt"); err != nil || len(files) != 1 {
+		t.Errorf("Resolve(data/*.txt) = %q, %v, want single file", files, err)
+	}
+}
@@ -0,0 +1,251 @@
+// Package cache stores measured files on disk, so unchanged files are not
+// parsed again on the next run.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bragov4ik/go-kys/pkg/assets"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Version of cached entries, it should be changed whenever results of the same
+// file and config may change, e.g. when metrics or report fields change
+const Version = "2"
+
+// Default limit of cache size in bytes
+const DefaultSize = 256 << 20
+
+// Cache of measured files in a directory
+type Cache struct {
+	// Directory with cache entries
+	Dir string
+	// Size of entries in bytes above which `Trim` removes least recently used ones,
+	// no limit if 0
+	MaxSize int64
+	// Receives errors of storing entries, they do not fail measuring as result
+	// is already known. Ignored if nil
+	Log func(error)
+}
+
+// Cached result of a single file
+type entry struct {
+	// `//go:embed` patterns of file, files matching them may change without
+	// change of the go file
+	Patterns []string `json:"patterns"`
+	// Hashes of embedded files by their paths
+	Embedded map[string]string `json:"embedded"`
+	// Measured file
+	Result report.File `json:"result"`
+}
+
+// Returns default cache directory inside user cache directory
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gokys"), nil
+}
+
+// Creates cache directory if it does not exist
+func Open(dir string, maxSize int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir, MaxSize: maxSize}, nil
+}
+
+// Measures file at given path like `report.MeasureFile`, result is taken from
+// cache if neither file, its embedded files nor config changed
+func (c *Cache) MeasureFile(path string, cfg *wmfp.Config) (report.File, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return report.File{}, err
+	}
+	key, err := Key(path, src, cfg)
+	if err != nil {
+		return report.File{}, err
+	}
+	if result, ok := c.get(key, path); ok {
+		return result, nil
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return report.File{}, err
+	}
+	result, err := report.Measure(fset, path, file, cfg)
+	if err != nil {
+		return report.File{}, err
+	}
+
+	if err := c.store(key, file, result); err != nil && c.Log != nil {
+		c.Log(fmt.Errorf("cache: %s: %w", path, err))
+	}
+	return result, nil
+}
+
+// Stores result of parsed file together with hashes of its embedded files
+func (c *Cache) store(key string, file *ast.File, result report.File) error {
+	e := entry{Patterns: assets.Patterns(file), Embedded: make(map[string]string), Result: result}
+	for _, asset := range result.Assets {
+		data, err := os.ReadFile(asset.Path)
+		if err != nil {
+			return err
+		}
+		e.Embedded[asset.Path] = hash(data)
+	}
+	return c.put(key, &e)
+}
+
+// Returns key of file with given path, content and config
+func Key(path string, src []byte, cfg *wmfp.Config) (string, error) {
+	config, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(Version), []byte(path), config, src} {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Returns cached result if embedded files did not change, every failure is a miss
+func (c *Cache) get(key, path string) (report.File, bool) {
+	name := c.path(key)
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return report.File{}, false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || !e.valid(filepath.Dir(path)) {
+		return report.File{}, false
+	}
+	// modification time is used as access time by `Trim`
+	now := time.Now()
+	_ = os.Chtimes(name, now, now)
+	return e.Result, true
+}
+
+// Checks that embedded files are the same as when entry was stored
+func (e *entry) valid(dir string) bool {
+	seen := make(map[string]bool)
+	for _, pattern := range e.Patterns {
+		paths, err := assets.Resolve(dir, pattern)
+		if err != nil {
+			return false
+		}
+		for _, p := range paths {
+			if seen[p] {
+				continue
+			}
+			seen[p] = true
+			data, err := os.ReadFile(p)
+			if err != nil || e.Embedded[p] != hash(data) {
+				return false
+			}
+		}
+	}
+	return len(seen) == len(e.Embedded)
+}
+
+// Writes entry atomically, so concurrent runs never read partial entries
+func (c *Cache) put(key string, e *entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	name := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), key+".tmp*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// Removes least recently used entries until cache fits into `MaxSize`
+func (c *Cache) Trim() error {
+	if c.MaxSize <= 0 {
+		return nil
+	}
+	type file struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var files []file
+	var total int64
+	err := filepath.WalkDir(c.Dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, ".json") {
+			return nil
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		files = append(files, file{p, info.Size(), info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	for _, f := range files {
+		if total <= c.MaxSize {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		total -= f.size
+	}
+	return nil
+}
+
+// Entries are spread over subdirectories by first byte of key
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key[:2], key+".json")
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
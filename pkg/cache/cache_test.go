@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bragov4ik/go-kys/pkg/assets"
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+const src = `package p
+
+import _ "embed"
+
+//go:embed *.sql
+var query string
+
+// Counts positive numbers
+func f(xs []int) (n int) {
+	for _, x := range xs {
+		if x > 0 {
+			n++
+		}
+	}
+	return
+}
+`
+
+func write(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasureFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	write(t, path, src)
+	write(t, filepath.Join(dir, "a.sql"), "SELECT 1;\n")
+
+	c, err := Open(filepath.Join(dir, "cache"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := wmfp.Config{
+		CycloComp: cyclo.Weights{If: 1, Rng: 1},
+		Halstead:  0.1,
+		Assets:    assets.Weights{SQL: assets.Kind{Byte: 1}},
+	}
+
+	tests := []struct {
+		name   string
+		change func()
+		hit    bool
+	}{
+		{"Cold", func() {}, false},
+		{"Unchanged", func() {}, true},
+		{"Source", func() { write(t, path, src+"\nfunc g() {}\n") }, false},
+		{"Config", func() { cfg.Halstead = 0.2 }, false},
+		{"Embedded", func() { write(t, filepath.Join(dir, "a.sql"), "SELECT 2;\n") }, false},
+		{"NewEmbedded", func() { write(t, filepath.Join(dir, "b.sql"), "SELECT 3;\n") }, false},
+		{"RemovedEmbedded", func() { os.Remove(filepath.Join(dir, "b.sql")) }, false},
+		{"Again", func() {}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.change()
+			want, err := report.MeasureFile(path, &cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := Key(path, data, &cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, hit := c.get(key, path); hit != tt.hit {
+				t.Errorf("cache hit = %v, want %v", hit, tt.hit)
+			}
+
+			got, err := c.MeasureFile(path, &cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("MeasureFile() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestMeasureFileUnwritable(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "p.go")
+	write(t, path, src)
+	write(t, filepath.Join(dir, "a.sql"), "SELECT 1;\n")
+	// entries can not be written below a regular file even by root
+	write(t, filepath.Join(dir, "cache"), "")
+
+	var logged []error
+	c := &Cache{Dir: filepath.Join(dir, "cache"), Log: func(err error) { logged = append(logged, err) }}
+	cfg := wmfp.Config{CycloComp: cyclo.Weights{If: 1}}
+	want, err := report.MeasureFile(path, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.MeasureFile(path, &cfg)
+	if err != nil {
+		t.Fatalf("MeasureFile() error = %v, want result without cache", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MeasureFile() = %+v, want %+v", got, want)
+	}
+	if len(logged) != 1 {
+		t.Errorf("logged errors = %v, want failed write", logged)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	dir := t.TempDir()
+	c, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []string{"aa01", "aa02", "bb03"}
+	for i, key := range keys {
+		if err := c.put(key, &entry{Result: report.File{Path: key}}); err != nil {
+			t.Fatal(err)
+		}
+		used := time.Now().Add(time.Duration(i-len(keys)) * time.Hour)
+		if err := os.Chtimes(c.path(key), used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+	info, err := os.Stat(c.path(keys[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.MaxSize = 2 * info.Size()
+	if err := c.Trim(); err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		_, err := os.Stat(c.path(key))
+		if exists := err == nil; exists != (i > 0) {
+			t.Errorf("entry %v exists = %v, want %v", key, exists, i > 0)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+// Package calibrate fits weights of WMFP metrics to known effort with
+// non-negative least squares.
+package calibrate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Known effort spent on target
+type Target struct {
+	// Go file, package directory or git revision range `from..to` or
+	// `from...to` (changes since merge base like in `git diff`)
+	Name string
+	// Spent minutes
+	Minutes float64
+}
+
+// Feature vector of target
+type Sample struct {
+	Target
+	// Raw counts behind every weight returned by `Weights`, i.e. score of
+	// counts with the weight set to 1 minus `Base`
+	Features []float64
+	// Score which does not depend on weights, e.g. base cyclomatic complexity
+	// of functions
+	Base float64
+}
+
+// Fitted weights and goodness of fit
+type Result struct {
+	// Weights in order of `Weights`
+	Weights []float64
+	// Minutes predicted for every sample
+	Predicted []float64
+	// Coefficient of determination on fitted samples, it does not show how
+	// well weights predict effort of other targets
+	R2 float64
+	// Root mean squared error in minutes on fitted samples
+	RMSE float64
+	// There are fewer samples than weights with non-zero features, so many
+	// weights fit equally well and fitted ones are arbitrary
+	Underdetermined bool
+}
+
+// Reads targets from csv with `target,minutes` rows, first row is skipped if
+// it is a header
+func ReadTargets(r io.Reader) ([]Target, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []Target
+	for i, record := range records {
+		minutes, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			if i == 0 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: invalid minutes %q", i+1, record[1])
+		}
+		targets = append(targets, Target{Name: record[0], Minutes: minutes})
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no targets")
+	}
+	return targets, nil
+}
+
+// Returns weights of config which are fitted, other fields (e.g. nesting
+// threshold) are kept as they are
+func Weights(cfg *wmfp.Config) []config.Field {
+	var weights []config.Field
+	for _, field := range config.Fields(cfg) {
+		if field.Value.Kind() == reflect.Float64 {
+			weights = append(weights, field)
+		}
+	}
+	return weights
+}
+
+// Returns copy of config with fitted weights
+func Apply(cfg wmfp.Config, weights []float64) wmfp.Config {
+	for i, field := range Weights(&cfg) {
+		field.Value.SetFloat(weights[i])
+	}
+	return cfg
+}
+
+// Measures features of target, non-weight fields of `cfg` are used as they
+// are. Revision ranges are measured as absolute change of features of go
+// files changed in range, git is run in current directory
+func Collect(t Target, cfg *wmfp.Config) (Sample, error) {
+	sample := Sample{Target: t, Features: make([]float64, len(Weights(cfg)))}
+	if info, err := os.Stat(t.Name); err == nil {
+		var files []string
+		if info.IsDir() {
+			if files, err = filepath.Glob(filepath.Join(t.Name, "*.go")); err != nil {
+				return sample, err
+			}
+		} else {
+			files = []string{t.Name}
+		}
+		for _, path := range files {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				return sample, err
+			}
+			features, base, err := probe(path, src, cfg)
+			if err != nil {
+				return sample, err
+			}
+			sample.add(features, base)
+		}
+		return sample, nil
+	}
+
+	if !strings.Contains(t.Name, "..") {
+		return sample, fmt.Errorf("%s: not a file, directory or revision range", t.Name)
+	}
+	return sample, collectRange(&sample, cfg)
+}
+
+func collectRange(sample *Sample, cfg *wmfp.Config) error {
+	from, to, symmetric := parseRange(sample.Name)
+	if symmetric {
+		base, err := git("merge-base", from, to)
+		if err != nil {
+			return err
+		}
+		from = strings.TrimSpace(base)
+	}
+	revs := []string{from, to}
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return err
+	}
+	changed, err := git("diff", "--name-only", revs[0], revs[1], "--", "*.go")
+	if err != nil {
+		return err
+	}
+	for _, name := range strings.Fields(changed) {
+		path := filepath.Join(strings.TrimSpace(root), name)
+		before, baseBefore, err := probeRevision(revs[0], name, path, cfg)
+		if err != nil {
+			return err
+		}
+		after, baseAfter, err := probeRevision(revs[1], name, path, cfg)
+		if err != nil {
+			return err
+		}
+		for i := range after {
+			sample.Features[i] += math.Abs(after[i] - before[i])
+		}
+		sample.Base += math.Abs(baseAfter - baseBefore)
+	}
+	return nil
+}
+
+// Splits revision range on the last `..`, revisions may contain dots
+// themselves. `from...to` is symmetric, missing revisions are `HEAD`
+func parseRange(name string) (from, to string, symmetric bool) {
+	i := strings.LastIndex(name, "..")
+	from, to = name[:i], name[i+2:]
+	if strings.HasSuffix(from, ".") {
+		from, symmetric = strings.TrimSuffix(from, "."), true
+	}
+	if from == "" {
+		from = "HEAD"
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+	return from, to, symmetric
+}
+
+// Measures file at revision, missing file has zero features
+func probeRevision(rev, name, path string, cfg *wmfp.Config) ([]float64, float64, error) {
+	src, err := git("show", rev+":"+name)
+	if err != nil {
+		return make([]float64, len(Weights(cfg))), 0, nil
+	}
+	return probe(path, []byte(src), cfg)
+}
+
+func git(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// Measures file once and scores its counts with every weight set to 1 in
+// turn, embedded files are looked up relative to `path`
+func probe(path string, src []byte, cfg *wmfp.Config) (features []float64, base float64, err error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return nil, 0, err
+	}
+	result, err := report.Measure(fset, path, file, cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	zero := Apply(*cfg, make([]float64, len(Weights(cfg))))
+	base = result.Counts.Score(&zero)
+	features = make([]float64, len(Weights(cfg)))
+	for i := range features {
+		unit := zero
+		Weights(&unit)[i].Value.SetFloat(1)
+		features[i] = result.Counts.Score(&unit) - base
+	}
+	return features, base, nil
+}
+
+func (s *Sample) add(features []float64, base float64) {
+	for i := range features {
+		s.Features[i] += features[i]
+	}
+	s.Base += base
+}
+
+// Fits non-negative weights so that base score plus weighted features is
+// closest to spent minutes. Fit is underdetermined if there are fewer samples
+// than weights with non-zero features
+func Fit(samples []Sample) (Result, error) {
+	if len(samples) == 0 {
+		return Result{}, errors.New("no samples")
+	}
+	a := make([][]float64, len(samples))
+	b := make([]float64, len(samples))
+	var mean float64
+	for i, s := range samples {
+		a[i] = s.Features
+		b[i] = s.Minutes - s.Base
+		mean += s.Minutes / float64(len(samples))
+	}
+
+	result := Result{Weights: NNLS(a, b), Predicted: make([]float64, len(samples))}
+	result.Underdetermined = len(samples) < active(samples)
+	var residual, total float64
+	for i, s := range samples {
+		result.Predicted[i] = s.Base + dot(s.Features, result.Weights)
+		residual += math.Pow(s.Minutes-result.Predicted[i], 2)
+		total += math.Pow(s.Minutes-mean, 2)
+	}
+	result.RMSE = math.Sqrt(residual / float64(len(samples)))
+	if total > 0 {
+		result.R2 = 1 - residual/total
+	}
+	return result, nil
+}
+
+// Returns number of weights with non-zero feature in any sample, other
+// weights do not change the fit
+func active(samples []Sample) (n int) {
+	for i := range samples[0].Features {
+		for _, s := range samples {
+			if s.Features[i] != 0 {
+				n++
+				break
+			}
+		}
+	}
+	return
+}
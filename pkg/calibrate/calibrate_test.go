@@ -0,0 +1,290 @@
+package calibrate
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadTargets(t *testing.T) {
+	data := `target,minutes
+# comment
+pkg/a, 30
+v1.0..v1.1,120.5
+`
+	got, err := ReadTargets(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Target{{"pkg/a", 30}, {"v1.0..v1.1", 120.5}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("ReadTargets() = %v, want %v", got, want)
+	}
+
+	for _, data := range []string{"a,1\nb,x\n", "a,1,2\n", "target,minutes\n"} {
+		if _, err := ReadTargets(strings.NewReader(data)); err == nil {
+			t.Errorf("ReadTargets(%q) should fail", data)
+		}
+	}
+}
+
+const (
+	srcA = `package a
+
+func f(x int) int {
+	if x > 0 {
+		return x + 1
+	}
+	return 0
+}
+`
+	srcB = `package a
+
+func g(xs []int) (sum int) {
+	for _, x := range xs {
+		if x > 0 && x < 10 {
+			sum += x
+		}
+	}
+	return
+}
+`
+)
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.go"), srcA)
+	writeFile(t, filepath.Join(dir, "b.go"), srcB)
+
+	cfg := wmfp.Config{}
+	var index = map[string]int{}
+	for i, field := range Weights(&cfg) {
+		index[field.Path] = i
+	}
+
+	file, err := Collect(Target{Name: filepath.Join(dir, "a.go")}, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkg, err := Collect(Target{Name: dir}, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.Base != 1 || pkg.Base != 2 {
+		t.Errorf("Base = %v, %v, want 1 and 2 functions", file.Base, pkg.Base)
+	}
+	checks := []struct {
+		sample Sample
+		path   string
+		want   float64
+	}{
+		{file, "cyclomatic.if", 1},
+		{file, "arithmetic.add", 1},
+		{pkg, "cyclomatic.if", 2},
+		{pkg, "cyclomatic.rng", 1},
+		{pkg, "cyclomatic.and", 1},
+		{pkg, "arithmetic.add_assign", 1},
+	}
+	for _, c := range checks {
+		if got := c.sample.Features[index[c.path]]; got != c.want {
+			t.Errorf("%s feature %s = %v, want %v", c.sample.Name, c.path, got, c.want)
+		}
+	}
+
+	if _, err := Collect(Target{Name: filepath.Join(dir, "missing")}, &cfg); err == nil {
+		t.Error("Collect() of missing file should fail")
+	}
+}
+
+func TestCollectRange(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=t", "GIT_AUTHOR_EMAIL=t@t",
+			"GIT_COMMITTER_NAME=t", "GIT_COMMITTER_EMAIL=t@t")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	run("init", "-q")
+	writeFile(t, filepath.Join(dir, "a.go"), srcA)
+	run("add", ".")
+	run("commit", "-qm", "a")
+	run("tag", "v1")
+	writeFile(t, filepath.Join(dir, "b.go"), srcB)
+	run("add", ".")
+	run("commit", "-qm", "b")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	cfg := wmfp.Config{}
+	want, err := Collect(Target{Name: "b.go"}, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"v1..HEAD", "v1...HEAD", "v1.."} {
+		got, err := Collect(Target{Name: name}, &cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Base != want.Base {
+			t.Errorf("%s: Base = %v, want %v", name, got.Base, want.Base)
+		}
+		for i := range want.Features {
+			if got.Features[i] != want.Features[i] {
+				t.Errorf("%s: Features[%d] = %v, want %v of added file", name, i, got.Features[i], want.Features[i])
+			}
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  string
+		symmetric bool
+	}{
+		{"v1.0..v1.1", "v1.0", "v1.1", false},
+		{"main...feature", "main", "feature", true},
+		{"HEAD~2..", "HEAD~2", "HEAD", false},
+		{"..v2", "HEAD", "v2", false},
+	}
+	for _, tt := range tests {
+		from, to, symmetric := parseRange(tt.name)
+		if from != tt.from || to != tt.to || symmetric != tt.symmetric {
+			t.Errorf("parseRange(%q) = %q, %q, %v, want %q, %q, %v",
+				tt.name, from, to, symmetric, tt.from, tt.to, tt.symmetric)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	samples := []Sample{
+		{Target: Target{Minutes: 1 + 2*1 + 0.5*4}, Base: 1, Features: []float64{1, 4, 0}},
+		{Target: Target{Minutes: 2 + 2*3 + 0.5*1}, Base: 2, Features: []float64{3, 1, 2}},
+		{Target: Target{Minutes: 0 + 2*2 + 0.5*2}, Base: 0, Features: []float64{2, 2, 5}},
+		{Target: Target{Minutes: 1 + 2*5 + 0.5*3}, Base: 1, Features: []float64{5, 3, 1}},
+	}
+	got, err := Fit(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []float64{2, 0.5, 0}
+	for i := range want {
+		if math.Abs(got.Weights[i]-want[i]) > 1e-9 {
+			t.Fatalf("Weights = %v, want %v", got.Weights, want)
+		}
+	}
+	if math.Abs(got.R2-1) > 1e-9 || got.RMSE > 1e-9 || got.Underdetermined {
+		t.Errorf("R2, RMSE, Underdetermined = %v, %v, %v, want exact fit", got.R2, got.RMSE, got.Underdetermined)
+	}
+	if got, err := Fit(samples[:2]); err != nil || !got.Underdetermined {
+		t.Errorf("Fit() of 2 samples and 3 weights = %+v, %v, want underdetermined", got, err)
+	}
+	if _, err := Fit(nil); err == nil {
+		t.Error("Fit() without samples should fail")
+	}
+}
+
+func TestApply(t *testing.T) {
+	truth := wmfp.Config{CycloComp: cyclo.Weights{If: 3, Rng: 2}, Halstead: 0.1}
+	dir := t.TempDir()
+	files := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "b.go")}
+	writeFile(t, files[0], srcA)
+	writeFile(t, files[1], srcB)
+
+	var samples []Sample
+	for _, path := range files {
+		r, err := report.MeasureFile(path, &truth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := Collect(Target{Name: path, Minutes: r.Score}, &truth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, s)
+	}
+	result, err := Fit(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fitted := Apply(truth, result.Weights)
+	for i, s := range samples {
+		r, err := report.MeasureFile(files[i], &fitted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(r.Score-s.Minutes) > 1e-6 || math.Abs(result.Predicted[i]-s.Minutes) > 1e-6 {
+			t.Errorf("fitted score = %v, predicted %v, want %v", r.Score, result.Predicted[i], s.Minutes)
+		}
+	}
+}
+
+func TestPredicted(t *testing.T) {
+	srcs := []string{
+		"package a\n\nvar s = \"plain text\"\n",
+		"package a\n\nvar s = \"%s is %d\"\n",
+		"package a\n\nvar s = `^[a-z]+\\d*$`\n",
+		"package a\n\nvar s = \"SELECT id FROM users\"\n",
+		srcA,
+	}
+	minutes := []float64{1, 10, 20, 30, 5}
+	cfg := config.Default()
+	dir := t.TempDir()
+
+	var samples []Sample
+	for i, src := range srcs {
+		path := filepath.Join(dir, fmt.Sprintf("%d.go", i))
+		writeFile(t, path, src)
+		s, err := Collect(Target{Name: path, Minutes: minutes[i]}, &cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		samples = append(samples, s)
+	}
+	result, err := Fit(samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fitted := Apply(cfg, result.Weights)
+	for i, s := range samples {
+		r, err := report.MeasureFile(s.Name, &fitted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(r.Score-result.Predicted[i]) > 1e-6 {
+			t.Errorf("%s: fitted score = %v, predicted %v", s.Name, r.Score, result.Predicted[i])
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package calibrate
+
+import "math"
+
+// Solves `min |a·x - b|` subject to `x >= 0` with Lawson-Hanson active set
+// method, `a` is a list of rows
+func NNLS(a [][]float64, b []float64) []float64 {
+	n := 0
+	if len(a) > 0 {
+		n = len(a[0])
+	}
+	x := make([]float64, n)
+	passive := make([]bool, n)
+	tol := 1e-10 * math.Max(1, norm(a))
+
+	for iter := 0; iter < 3*n+3; iter++ {
+		w := gradient(a, b, x)
+		j := -1
+		for k := range w {
+			if !passive[k] && w[k] > tol && (j < 0 || w[k] > w[j]) {
+				j = k
+			}
+		}
+		if j < 0 {
+			break
+		}
+		passive[j] = true
+
+		for {
+			z := leastSquares(a, b, passive)
+			// step towards z until some passive variable becomes zero
+			alpha := 1.0
+			feasible := true
+			for k := range z {
+				if passive[k] && z[k] <= tol {
+					feasible = false
+					if step := x[k] / (x[k] - z[k]); step < alpha {
+						alpha = step
+					}
+				}
+			}
+			if feasible {
+				copy(x, z)
+				break
+			}
+			for k := range x {
+				x[k] += alpha * (z[k] - x[k])
+				if passive[k] && x[k] <= tol {
+					passive[k] = false
+					x[k] = 0
+				}
+			}
+		}
+	}
+	return x
+}
+
+// Returns `aᵀ(b - a·x)`
+func gradient(a [][]float64, b, x []float64) []float64 {
+	w := make([]float64, len(x))
+	for i, row := range a {
+		r := b[i] - dot(row, x)
+		for k, v := range row {
+			w[k] += v * r
+		}
+	}
+	return w
+}
+
+// Solves unconstrained least squares for variables in `passive` set with
+// normal equations, other variables are zero
+func leastSquares(a [][]float64, b []float64, passive []bool) []float64 {
+	var cols []int
+	for k, p := range passive {
+		if p {
+			cols = append(cols, k)
+		}
+	}
+	m := len(cols)
+	// augmented matrix of normal equations
+	ata := make([][]float64, m)
+	for i := range ata {
+		ata[i] = make([]float64, m+1)
+	}
+	for r, row := range a {
+		for i, ci := range cols {
+			for j, cj := range cols {
+				ata[i][j] += row[ci] * row[cj]
+			}
+			ata[i][m] += row[ci] * b[r]
+		}
+	}
+
+	solution := solve(ata)
+	x := make([]float64, len(passive))
+	for i, c := range cols {
+		x[c] = solution[i]
+	}
+	return x
+}
+
+// Solves linear system given by augmented matrix with gaussian elimination,
+// variables of singular (e.g. collinear) columns are zero
+func solve(m [][]float64) []float64 {
+	n := len(m)
+	eps := 1e-12 * math.Max(1, norm(m))
+	pivots := make([]int, n)
+	row := 0
+	for col := 0; col < n; col++ {
+		pivots[col] = -1
+		if row >= n {
+			continue
+		}
+		best := row
+		for r := row; r < n; r++ {
+			if math.Abs(m[r][col]) > math.Abs(m[best][col]) {
+				best = r
+			}
+		}
+		if math.Abs(m[best][col]) < eps {
+			continue
+		}
+		m[row], m[best] = m[best], m[row]
+		for r := 0; r < n; r++ {
+			if r == row || m[r][col] == 0 {
+				continue
+			}
+			f := m[r][col] / m[row][col]
+			for c := col; c <= n; c++ {
+				m[r][c] -= f * m[row][c]
+			}
+		}
+		pivots[col] = row
+		row++
+	}
+
+	x := make([]float64, n)
+	for col, r := range pivots {
+		if r >= 0 {
+			x[col] = m[r][n] / m[r][col]
+		}
+	}
+	return x
+}
+
+func dot(a, b []float64) (s float64) {
+	for i := range a {
+		s += a[i] * b[i]
+	}
+	return
+}
+
+// Returns maximum absolute value of matrix
+func norm(a [][]float64) (max float64) {
+	for _, row := range a {
+		for _, v := range row {
+			max = math.Max(max, math.Abs(v))
+		}
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package calibrate
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNNLS(t *testing.T) {
+	tests := []struct {
+		name string
+		a    [][]float64
+		b    []float64
+		want []float64
+	}{
+		{"exact", [][]float64{{1, 0}, {0, 1}, {1, 1}}, []float64{2, 3, 5}, []float64{2, 3}},
+		{"negative is clamped", [][]float64{{1, 0}, {0, 1}}, []float64{2, -3}, []float64{2, 0}},
+		{"zero column", [][]float64{{1, 0}, {2, 0}}, []float64{1, 2}, []float64{1, 0}},
+		{"collinear", [][]float64{{1, 1}, {2, 2}}, []float64{2, 4}, nil},
+		{
+			"overdetermined",
+			[][]float64{{1, 2}, {2, 1}, {3, 3}, {1, 0}},
+			[]float64{5, 4, 9.5, 1},
+			[]float64{1.0366, 2.0732},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NNLS(tt.a, tt.b)
+			for _, v := range got {
+				if v < 0 {
+					t.Fatalf("NNLS() = %v, has negative values", got)
+				}
+			}
+			if tt.want == nil {
+				// any solution with zero residual
+				if r := gradient(tt.a, tt.b, got); math.Abs(r[0]) > 1e-9 || dot(tt.a[0], got) != tt.b[0] {
+					t.Errorf("NNLS() = %v, is not a solution", got)
+				}
+				return
+			}
+			for i := range tt.want {
+				if math.Abs(got[i]-tt.want[i]) > 1e-3 {
+					t.Errorf("NNLS() = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
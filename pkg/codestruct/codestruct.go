@@ -1,42 +1,253 @@
 // Package with metric which checks general code structure.
 package codestruct
 
-import "go/ast"
+import (
+	"go/ast"
+	"strconv"
+	"strings"
+)
 
 // Weights for metric
 type Weights struct {
 	// Function declaration weight
-	Func float64 `xml:"func"`
-	// Structure declaration weight
-	Struct float64 `xml:"struct"`
+	Func float64 `xml:"func" yaml:"func" json:"func" toml:"func"`
+	// Named structure declaration weight
+	Struct float64 `xml:"struct" yaml:"struct" json:"struct" toml:"struct"`
+	// Anonymous structure weight
+	AnonymousStruct float64 `xml:"anonymous_struct" yaml:"anonymous_struct" json:"anonymous_struct" toml:"anonymous_struct"`
+	// Weight of other type declarations (e.g. `type ID int`)
+	NamedType float64 `xml:"named_type" yaml:"named_type" json:"named_type" toml:"named_type"`
+	// Weight of every named structure field
+	Field float64 `xml:"field" yaml:"field" json:"field" toml:"field"`
+	// Weight of every embedded structure field
+	EmbeddedField float64 `xml:"embedded_field" yaml:"embedded_field" json:"embedded_field" toml:"embedded_field"`
+	// Weight of every key of structure field tags
+	Tag float64 `xml:"tag" yaml:"tag" json:"tag" toml:"tag"`
 	// Interface declaration weight
-	Interface float64 `xml:"interface"`
+	Interface float64 `xml:"interface" yaml:"interface" json:"interface" toml:"interface"`
+	// Weight of every interface method
+	Method float64 `xml:"method" yaml:"method" json:"method" toml:"method"`
+	// Weight of every embedded interface or type constraint
+	EmbeddedInterface float64 `xml:"embedded_interface" yaml:"embedded_interface" json:"embedded_interface" toml:"embedded_interface"`
+	// Weight of every function parameter
+	Param float64 `xml:"param" yaml:"param" json:"param" toml:"param"`
+	// Weight of every function result
+	Result float64 `xml:"result" yaml:"result" json:"result" toml:"result"`
+	// Additional weight of every named result
+	NamedResult float64 `xml:"named_result" yaml:"named_result" json:"named_result" toml:"named_result"`
+	// Additional weight of variadic parameter
+	Variadic float64 `xml:"variadic" yaml:"variadic" json:"variadic" toml:"variadic"`
+	// Additional weight of every parameter of function type
+	FuncParam float64 `xml:"func_param" yaml:"func_param" json:"func_param" toml:"func_param"`
+	// Weight of method receiver
+	Receiver float64 `xml:"receiver" yaml:"receiver" json:"receiver" toml:"receiver"`
+	// Additional weight of pointer receiver
+	PointerReceiver float64 `xml:"pointer_receiver" yaml:"pointer_receiver" json:"pointer_receiver" toml:"pointer_receiver"`
+}
+
+// Raw counts of code structure elements, fields are named after weights
+type Counts struct {
+	// Number of function declarations
+	Func float64 `json:"func"`
+	// Number of named structures
+	Struct float64 `json:"struct"`
+	// Number of anonymous structures
+	AnonymousStruct float64 `json:"anonymous_struct"`
+	// Number of other type declarations
+	NamedType float64 `json:"named_type"`
+	// Number of named structure fields
+	Field float64 `json:"field"`
+	// Number of embedded structure fields
+	EmbeddedField float64 `json:"embedded_field"`
+	// Number of keys of structure field tags
+	Tag float64 `json:"tag"`
+	// Number of interfaces
+	Interface float64 `json:"interface"`
+	// Number of interface methods
+	Method float64 `json:"method"`
+	// Number of embedded interfaces and type constraints
+	EmbeddedInterface float64 `json:"embedded_interface"`
+	// Number of function parameters
+	Param float64 `json:"param"`
+	// Number of function results
+	Result float64 `json:"result"`
+	// Number of named results
+	NamedResult float64 `json:"named_result"`
+	// Number of variadic parameters
+	Variadic float64 `json:"variadic"`
+	// Number of parameters of function type
+	FuncParam float64 `json:"func_param"`
+	// Number of method receivers
+	Receiver float64 `json:"receiver"`
+	// Number of pointer receivers
+	PointerReceiver float64 `json:"pointer_receiver"`
+}
+
+// Computes weighted result from counts
+func (c Counts) Score(cfg *Weights) float64 {
+	return c.Func*cfg.Func + c.Struct*cfg.Struct + c.AnonymousStruct*cfg.AnonymousStruct +
+		c.NamedType*cfg.NamedType + c.Field*cfg.Field + c.EmbeddedField*cfg.EmbeddedField + c.Tag*cfg.Tag +
+		c.Interface*cfg.Interface + c.Method*cfg.Method + c.EmbeddedInterface*cfg.EmbeddedInterface +
+		c.Param*cfg.Param + c.Result*cfg.Result + c.NamedResult*cfg.NamedResult + c.Variadic*cfg.Variadic +
+		c.FuncParam*cfg.FuncParam + c.Receiver*cfg.Receiver + c.PointerReceiver*cfg.PointerReceiver
 }
 
 // Intermidiate state for code structure metric
 type Metric struct {
 	// Config with weights
 	Config Weights
-	comp   float64
+	counts Counts
+	// structures declared by type declarations
+	named map[*ast.StructType]bool
 }
 
 // Parses ast node and collects result of metric
-func (m *Metric) ParseNode(n ast.Node) { m.comp += getCodeStructComp(n, &m.Config) }
+func (m *Metric) ParseNode(n ast.Node) { m.count(n, &m.counts) }
+
+// Parses node like `ParseNode`, but returns counts of node instead of
+// collecting them
+func (m *Metric) Count(n ast.Node) (c Counts) {
+	m.count(n, &c)
+	return
+}
+
+func (m *Metric) count(n ast.Node, c *Counts) {
+	// type specs are visited before their types, so named structures are known in advance
+	if v, ok := n.(*ast.TypeSpec); ok {
+		if st, ok := v.Type.(*ast.StructType); ok {
+			if m.named == nil {
+				m.named = make(map[*ast.StructType]bool)
+			}
+			m.named[st] = true
+		}
+	}
+	countCodeStruct(n, m.named, c)
+}
 
 // Returns final result of metric
-func (m *Metric) Finish() float64 { return m.comp }
+func (m *Metric) Finish() float64 { return m.counts.Score(&m.Config) }
 
-func getCodeStructComp(n ast.Node, cfg *Weights) float64 {
-	var comp float64
+// Returns raw counts collected so far
+func (m *Metric) Counts() Counts { return m.counts }
 
-	switch n.(type) {
+func countCodeStruct(n ast.Node, named map[*ast.StructType]bool, c *Counts) {
+	switch v := n.(type) {
 	case *ast.StructType:
-		comp = cfg.Struct
+		if named[v] {
+			c.Struct++
+		} else {
+			c.AnonymousStruct++
+		}
+		countStructSize(v, c)
+	case *ast.TypeSpec:
+		switch v.Type.(type) {
+		case *ast.StructType, *ast.InterfaceType:
+		default:
+			c.NamedType++
+		}
 	case *ast.FuncDecl:
-		comp = cfg.Func
+		c.Func++
+		countSignature(v, c)
 	case *ast.InterfaceType:
-		comp = cfg.Interface
+		c.Interface++
+		countInterfaceSize(v, c)
 	}
+}
+
+func countStructSize(st *ast.StructType, c *Counts) {
+	for _, field := range fieldList(st.Fields) {
+		if len(field.Names) == 0 {
+			c.EmbeddedField++
+		} else {
+			c.Field += float64(len(field.Names))
+		}
+		if field.Tag != nil {
+			c.Tag += float64(len(tagKeys(field.Tag.Value)))
+		}
+	}
+}
 
-	return comp
+func countInterfaceSize(it *ast.InterfaceType, c *Counts) {
+	for _, field := range fieldList(it.Methods) {
+		if len(field.Names) == 0 {
+			c.EmbeddedInterface++
+		} else {
+			c.Method += float64(len(field.Names))
+		}
+	}
+}
+
+// Returns keys of struct tag literal in conventional format `key:"value" key2:"value"`
+func tagKeys(literal string) []string {
+	tag, err := strconv.Unquote(literal)
+	if err != nil {
+		return nil
+	}
+
+	var keys []string
+	for {
+		tag = strings.TrimLeft(tag, " ")
+		colon := strings.Index(tag, ":\"")
+		if colon <= 0 || strings.ContainsAny(tag[:colon], " \"") {
+			return keys
+		}
+		keys = append(keys, tag[:colon])
+
+		// skip quoted value
+		value := tag[colon+1:]
+		end := 1
+		for end < len(value) && value[end] != '"' {
+			if value[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(value) {
+			return keys
+		}
+		tag = value[end+1:]
+	}
+}
+
+func countSignature(fd *ast.FuncDecl, c *Counts) {
+	if fd.Recv != nil && len(fd.Recv.List) > 0 {
+		c.Receiver++
+		if _, ok := fd.Recv.List[0].Type.(*ast.StarExpr); ok {
+			c.PointerReceiver++
+		}
+	}
+
+	for _, param := range fieldList(fd.Type.Params) {
+		n := float64(namesCount(param))
+		c.Param += n
+		switch param.Type.(type) {
+		case *ast.Ellipsis:
+			c.Variadic++
+		case *ast.FuncType:
+			c.FuncParam += n
+		}
+	}
+
+	for _, result := range fieldList(fd.Type.Results) {
+		n := float64(namesCount(result))
+		c.Result += n
+		if len(result.Names) > 0 {
+			c.NamedResult += n
+		}
+	}
+}
+
+func fieldList(list *ast.FieldList) []*ast.Field {
+	if list == nil {
+		return nil
+	}
+	return list.List
+}
+
+// Unnamed fields still declare one parameter
+func namesCount(field *ast.Field) int {
+	if len(field.Names) == 0 {
+		return 1
+	}
+	return len(field.Names)
 }
@@ -38,3 +38,106 @@ func TestCodeStructComp(t *testing.T) {
 		t.Errorf("TestCodeStructComp got %v, want %v", got, want)
 	}
 }
+
+func TestSignatureComp(t *testing.T) {
+	cfg := Weights{
+		Param:           1,
+		Result:          10,
+		NamedResult:     100,
+		Variadic:        1000,
+		FuncParam:       10000,
+		Receiver:        100000,
+		PointerReceiver: 1000000,
+	}
+	tests := []struct {
+		name string
+		src  string
+		want float64
+	}{
+		{"Empty", `func f() {}`, 0},
+		{"Params", `func f(a, b int, c string) {}`, 3},
+		{"Unnamed", `func f(int, string) (int, error) {}`, 22},
+		{"NamedResults", `func f() (a, b int) {}`, 220},
+		{"Variadic", `func f(format string, args ...interface{}) {}`, 1002},
+		{"FuncParams", `func f(a, b func() error) {}`, 20002},
+		{"Method", `func (T) f() {}`, 100000},
+		{"PointerMethod", `func (t *T) f() {}`, 1100000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+tt.src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fd := file.Decls[0].(*ast.FuncDecl)
+			var c Counts
+			countSignature(fd, &c)
+			if got := c.Score(&cfg); got != tt.want {
+				t.Errorf("score of signature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSizeComp(t *testing.T) {
+	cfg := Weights{
+		Struct:            1,
+		AnonymousStruct:   2,
+		NamedType:         3,
+		Field:             10,
+		EmbeddedField:     100,
+		Tag:               1000,
+		Interface:         10000,
+		Method:            100000,
+		EmbeddedInterface: 1000000,
+	}
+	tests := []struct {
+		name string
+		src  string
+		want float64
+	}{
+		{"EmptyStruct", `type T struct{}`, 1},
+		{"Fields", `type T struct { a, b int; c string }`, 31},
+		{"Embedded", `type T struct { io.Reader; *U }`, 201},
+		{"Tags", "type T struct { A int `json:\"a,omitempty\" xml:\"a\"`; B int `yaml:\"b\"`}", 3021},
+		{"Anonymous", `var a struct { x int }`, 12},
+		{"NestedAnonymous", `type T struct { a struct { b int } }`, 23},
+		{"NamedType", `type ID int; type F = func()`, 6},
+		{"Interface", `type I interface { io.Reader; A(); B() error }`, 1210000},
+		{"Constraint", `type N interface { ~int | ~float64 }`, 1010000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+tt.src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := Metric{Config: cfg}
+			ast.Inspect(file, func(n ast.Node) bool {
+				m.ParseNode(n)
+				return true
+			})
+			if got := m.Finish(); got != tt.want {
+				t.Errorf("Finish() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagKeys(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want int
+	}{
+		{"``", 0},
+		{"`json:\"a\"`", 1},
+		{"`json:\"a,omitempty\" validate:\"required,min=1\"`", 2},
+		{`"json:\"a\\\"b\" xml:\"c\""`, 2},
+		{"`not a tag`", 0},
+	}
+	for _, tt := range tests {
+		if got := len(tagKeys(tt.tag)); got != tt.want {
+			t.Errorf("len(tagKeys(%v)) = %v, want %v", tt.tag, got, tt.want)
+		}
+	}
+}
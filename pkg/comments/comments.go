@@ -9,26 +9,43 @@ import (
 // Config for metric
 type Weights struct {
 	// Weight of each word in every comment
-	Word float64 `xml:"word"`
+	Word float64 `xml:"word" yaml:"word" json:"word" toml:"word"`
 }
 
+// Raw counts of metric
+type Counts struct {
+	// Number of words in comments
+	Word float64 `json:"word"`
+}
+
+// Computes weighted result from counts
+func (c Counts) Score(config *Weights) float64 { return c.Word * config.Word }
+
 // Intermidiate state of metric
 type Metric struct {
 	// Config with weights
 	Config Weights
-	score  float64
+	counts Counts
 }
 
 // Parses ast node and collects metric result
-func (m *Metric) ParseNode(n ast.Node) {
-	if v, ok := n.(*ast.Comment); ok {
-		m.score += getCommentComp(v, &m.Config)
-	}
+func (m *Metric) ParseNode(n ast.Node) { countComment(n, &m.counts) }
+
+// Parses node like `ParseNode`, but returns counts of node instead of
+// collecting them
+func (m *Metric) Count(n ast.Node) (c Counts) {
+	countComment(n, &c)
+	return
 }
 
 // Returns metric result
-func (m Metric) Finish() float64 { return m.score }
+func (m Metric) Finish() float64 { return m.counts.Score(&m.Config) }
+
+// Returns raw counts collected so far
+func (m Metric) Counts() Counts { return m.counts }
 
-func getCommentComp(comment *ast.Comment, config *Weights) float64 {
-	return float64(len(strings.Fields(comment.Text))) * config.Word
+func countComment(n ast.Node, c *Counts) {
+	if v, ok := n.(*ast.Comment); ok {
+		c.Word += float64(len(strings.Fields(v.Text)))
+	}
 }
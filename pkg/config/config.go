@@ -0,0 +1,160 @@
+// Package config reads and writes WMFP configuration in XML, YAML, JSON and
+// TOML formats.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Format of configuration file
+type Format string
+
+// Supported formats
+const (
+	XML  Format = "xml"
+	YAML Format = "yaml"
+	JSON Format = "json"
+	TOML Format = "toml"
+)
+
+// Root element of XML configuration
+type xmlConfig struct {
+	XMLName xml.Name `xml:"config"`
+	wmfp.Config
+}
+
+// Encoded config file: base configuration followed by overlays
+type document struct {
+	XMLName     xml.Name `xml:"config" yaml:"-" json:"-" toml:"-"`
+	wmfp.Config `yaml:",inline"`
+	// Overlays with only weights set by them, see `overlayValue`
+	Overlays []interface{} `xml:"overlay" yaml:"overlay,omitempty" json:"overlay,omitempty" toml:"overlay,omitempty"`
+}
+
+// Returns format with given name or file extension (like `yml` or `.yaml`)
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "xml":
+		return XML, nil
+	case "yaml", "yml":
+		return YAML, nil
+	case "json":
+		return JSON, nil
+	case "toml":
+		return TOML, nil
+	}
+	return "", fmt.Errorf("unknown config format %q", name)
+}
+
+var (
+	tomlTable = regexp.MustCompile(`^\[[\w.-]+\]\s*(#.*)?$`)
+	tomlKey   = regexp.MustCompile(`^[\w-]+\s*=`)
+)
+
+// Detects format by file extension, or by content if extension is unknown
+func Detect(path string, data []byte) Format {
+	if format, err := ParseFormat(filepath.Ext(path)); err == nil {
+		return format
+	}
+
+	content := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(content, []byte("<")):
+		return XML
+	case bytes.HasPrefix(content, []byte("{")):
+		return JSON
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if tomlTable.MatchString(line) || tomlKey.MatchString(line) {
+			return TOML
+		}
+		break
+	}
+	return YAML
+}
+
+// Decodes configuration on top of `cfg`, values missing in data are left unchanged
+func Decode(data []byte, format Format, cfg *wmfp.Config) error {
+	switch format {
+	case XML:
+		wrapper := xmlConfig{Config: *cfg}
+		if err := xml.Unmarshal(data, &wrapper); err != nil {
+			return err
+		}
+		*cfg = wrapper.Config
+		return nil
+	case YAML:
+		return yaml.Unmarshal(data, cfg)
+	case JSON:
+		return json.Unmarshal(data, cfg)
+	case TOML:
+		_, err := toml.Decode(string(data), cfg)
+		return err
+	}
+	return fmt.Errorf("unknown config format %q", format)
+}
+
+// Encodes configuration in given format
+func Encode(cfg *wmfp.Config, format Format) ([]byte, error) {
+	return encode(&document{Config: *cfg}, format)
+}
+
+func encode(doc *document, format Format) ([]byte, error) {
+	switch format {
+	case XML:
+		data, err := xml.MarshalIndent(doc, "", "    ")
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), append(data, '\n')...), nil
+	case YAML:
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case JSON:
+		data, err := json.MarshalIndent(doc, "", "  ")
+		return append(data, '\n'), err
+	case TOML:
+		var buf bytes.Buffer
+		err := toml.NewEncoder(&buf).Encode(doc)
+		return buf.Bytes(), err
+	}
+	return nil, fmt.Errorf("unknown config format %q", format)
+}
+
+// Reads and validates configuration file in any supported format, weights
+// missing in file are zero. Overlays are checked but not returned, see
+// `LoadWithDefaults`
+func Load(path string) (wmfp.Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return wmfp.Config{}, err
+	}
+	loaded, err := parse(path, data, wmfp.Config{})
+	if err != nil {
+		return wmfp.Config{}, err
+	}
+	return loaded.Config, nil
+}
+
+func wrapError(path string, err error) error { return fmt.Errorf("%s: %w", path, err) }
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+func TestLoadAndConvert(t *testing.T) {
+	cfg, err := Load("default.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CycloComp.For != 2 || cfg.Nesting.Threshold != 3 || cfg.InlineData.Depth != 0.1 || cfg.Halstead != 0.1 {
+		t.Fatalf("Load() = %+v, weights are not read", cfg)
+	}
+
+	for _, format := range []Format{XML, YAML, JSON, TOML} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := Encode(&cfg, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Detect("config", data); got != format {
+				t.Errorf("Detect() = %v, want %v", got, format)
+			}
+
+			var got wmfp.Config
+			if err := Decode(data, format, &got); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, cfg) {
+				t.Errorf("Decode(Encode()) = %+v, want %+v", got, cfg)
+			}
+		})
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"unknown.yaml":  "cyclomatic:\n  iff: 1\n",
+		"negative.json": `{"halstead": -1}`,
+		"overlay.toml":  "[[overlay]]\n[overlay.cyclomatic]\nif = 1\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Load(path); err == nil {
+			t.Errorf("Load(%v) should fail", name)
+		}
+	}
+}
+
+func TestDecodeKeepsValues(t *testing.T) {
+	tests := []struct {
+		format Format
+		data   string
+	}{
+		{XML, `<config><cyclomatic><if>5</if></cyclomatic></config>`},
+		{YAML, "cyclomatic:\n  if: 5\n"},
+		{JSON, `{"cyclomatic": {"if": 5}}`},
+		{TOML, "[cyclomatic]\nif = 5\n"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			cfg := wmfp.Config{Halstead: 1}
+			cfg.CycloComp.For = 2
+			if err := Decode([]byte(tt.data), tt.format, &cfg); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.CycloComp.If != 5 || cfg.CycloComp.For != 2 || cfg.Halstead != 1 {
+				t.Errorf("Decode() = %+v, want if 5, for 2 and halstead 1", cfg)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		path string
+		data string
+		want Format
+	}{
+		{"a.yml", "", YAML},
+		{"a.TOML", "", TOML},
+		{".gokys", "  <config/>", XML},
+		{".gokys", "{}", JSON},
+		{".gokys", "# comment\n\n[inline]\nint = 1", TOML},
+		{".gokys", "halstead = 1", TOML},
+		{".gokys", "# comment\nhalstead: 1", YAML},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.path, []byte(tt.data)); got != tt.want {
+			t.Errorf("Detect(%v, %q) = %v, want %v", tt.path, tt.data, got, tt.want)
+		}
+	}
+
+	if _, err := ParseFormat("ini"); err == nil {
+		t.Error("ParseFormat(ini) should fail")
+	}
+}
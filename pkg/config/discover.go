@@ -0,0 +1,230 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+//go:embed default.xml
+var defaultXML []byte
+
+// Source of values which were not set by any config file
+const DefaultSource = "default"
+
+// Names of project config files, looked up from target directory upwards
+var ProjectNames = []string{".gokys.xml", ".gokys.yaml", ".gokys.yml", ".gokys.json", ".gokys.toml"}
+
+// Names of user config files in `$XDG_CONFIG_HOME/gokys`
+var UserNames = []string{"config.xml", "config.yaml", "config.yml", "config.json", "config.toml"}
+
+// Returns default configuration embedded into binary
+func Default() wmfp.Config {
+	var cfg wmfp.Config
+	if err := Decode(defaultXML, XML, &cfg); err != nil {
+		panic(err)
+	}
+	return cfg
+}
+
+// Effective configuration together with origin of its values
+type Loaded struct {
+	// Configuration with defaults for values missing in file
+	Config wmfp.Config
+	// Path to config file, empty if only defaults are used
+	Path string
+	// Keys which were set in config file
+	Keys []Key
+	// Weights missing in config file which were taken from defaults
+	Warnings []Issue
+	// Overlays applied to matching files, see `For`
+	Overlays []Overlay
+}
+
+// Configuration value with its origin
+type Value struct {
+	// Dot separated path of element names, e.g. `cyclomatic.if`
+	Path string
+	// Formatted value
+	Value string
+	// Path to config file or `DefaultSource`
+	Source string
+}
+
+// Reads and validates config file on top of default configuration
+func LoadWithDefaults(path string) (*Loaded, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Decodes and validates contents of config file on top of default
+// configuration, `path` is used to detect format and in messages
+func Parse(path string, data []byte) (*Loaded, error) {
+	return parse(path, data, Default())
+}
+
+// Decodes and validates contents of config file on top of `base`
+func parse(path string, data []byte, base wmfp.Config) (*Loaded, error) {
+	loaded := &Loaded{Config: base, Path: path}
+	format := Detect(path, data)
+	var err error
+	if loaded.Keys, err = Keys(data, format); err != nil {
+		return nil, wrapError(path, err)
+	}
+	if err := Decode(data, format, &loaded.Config); err != nil {
+		return nil, wrapError(path, err)
+	}
+	if loaded.Overlays, err = decodeOverlays(data, format, loaded.Keys); err != nil {
+		return nil, wrapError(path, err)
+	}
+	var errs Errors
+	loaded.Warnings, err = Validate(path, loaded.Keys, &loaded.Config)
+	if err != nil {
+		errs = append(errs, err.(Errors)...)
+	}
+	if err := ValidateOverlays(path, loaded.Overlays); err != nil {
+		errs = append(errs, err.(Errors)...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return loaded, nil
+}
+
+// Finds and loads configuration for files in directory `dir`: explicit `path`
+// if it is not empty, project config in `dir` or its parents, user config in
+// `$XDG_CONFIG_HOME/gokys`, or embedded defaults
+func Resolve(path, dir string) (*Loaded, error) {
+	if path == "" {
+		path = Discover(dir)
+	}
+	if path == "" {
+		return &Loaded{Config: Default()}, nil
+	}
+	return LoadWithDefaults(path)
+}
+
+// Configs resolved for directories, e.g. by a long running server. Config of
+// directory is read again when another file is discovered for it or the file
+// changes. It is not safe for concurrent use
+type Cache struct {
+	// Explicit config file for all directories, configs are discovered if empty
+	Path string
+
+	dirs map[string]cached
+}
+
+type cached struct {
+	loaded *Loaded
+	// config file with its size and modification time when it was read
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Returns configuration for files in directory `dir` like `Resolve`, failed
+// configs are not cached, so they are read again on the next call
+func (c *Cache) Resolve(dir string) (*Loaded, error) {
+	path := c.Path
+	if path == "" {
+		path = Discover(dir)
+	} else {
+		dir = ""
+	}
+	current := cached{path: path}
+	if path != "" {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		current.size, current.modTime = info.Size(), info.ModTime()
+	}
+	if prev, ok := c.dirs[dir]; ok && prev.path == current.path && prev.size == current.size && prev.modTime.Equal(current.modTime) {
+		return prev.loaded, nil
+	}
+
+	var err error
+	if current.loaded, err = Resolve(path, dir); err != nil {
+		return nil, err
+	}
+	if c.dirs == nil {
+		c.dirs = make(map[string]cached)
+	}
+	c.dirs[dir] = current
+	return current.loaded, nil
+}
+
+// Returns path to project or user config for directory `dir`, empty if there
+// is no config
+func Discover(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	for {
+		if path := findFile(dir, ProjectNames); path != "" {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+	return findFile(filepath.Join(userConfigDir(), "gokys"), UserNames)
+}
+
+func findFile(dir string, names []string) string {
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+func userConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config")
+}
+
+// Returns all values of effective configuration with their origins
+func (l *Loaded) Values() []Value {
+	set := make(map[string]bool, len(l.Keys))
+	for _, key := range l.Keys {
+		set[key.Path] = true
+	}
+
+	var values []Value
+	for _, field := range Fields(&l.Config) {
+		source := DefaultSource
+		if set[field.Path] {
+			source = l.Path
+		}
+		values = append(values, Value{field.Path, field.String(), source})
+	}
+	for i, o := range l.Overlays {
+		source := fmt.Sprintf("%s (overlay %q)", l.Path, o.Pattern)
+		for _, field := range Fields(&l.Config) {
+			if value, ok := o.values[field.Path]; ok {
+				formatted := Field{field.Path, value}.String()
+				path := fmt.Sprintf("%s.%d.%s", OverlayKey, i, field.Path)
+				values = append(values, Value{path, formatted, source})
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefault(t *testing.T) {
+	cfg := Default()
+	if cfg.CycloComp.For != 2 || cfg.Halstead != 0.1 || cfg.Nesting.Threshold != 3 {
+		t.Errorf("Default() = %+v, embedded config is not read", cfg)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	root := t.TempDir()
+	xdg := filepath.Join(root, "xdg")
+	project := filepath.Join(root, "project")
+	nested := filepath.Join(project, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+
+	if got := Discover(nested); got != "" {
+		t.Errorf("Discover() = %v, want no config", got)
+	}
+
+	user := filepath.Join(xdg, "gokys", "config.yaml")
+	writeFile(t, user, "halstead: 1\n")
+	if got := Discover(nested); got != user {
+		t.Errorf("Discover() = %v, want user config %v", got, user)
+	}
+
+	local := filepath.Join(project, ".gokys.toml")
+	writeFile(t, local, "halstead = 2\n")
+	if got := Discover(nested); got != local {
+		t.Errorf("Discover() = %v, want project config %v", got, local)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+
+	loaded, err := Resolve("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Path != "" || loaded.Config != Default() {
+		t.Errorf("Resolve() = %+v, want defaults", loaded)
+	}
+
+	path := filepath.Join(dir, ".gokys.yaml")
+	writeFile(t, path, "cyclomatic:\n  if: 7\n")
+	loaded, err = Resolve("", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Path != path || loaded.Config.CycloComp.If != 7 || loaded.Config.CycloComp.For != 2 {
+		t.Errorf("Resolve() = %+v, want project config on top of defaults", loaded)
+	}
+
+	sources := map[string]string{}
+	for _, v := range loaded.Values() {
+		sources[v.Path] = v.Source + "=" + v.Value
+	}
+	if got, want := sources["cyclomatic.if"], path+"=7"; got != want {
+		t.Errorf("cyclomatic.if = %v, want %v", got, want)
+	}
+	if got, want := sources["cyclomatic.for"], DefaultSource+"=2"; got != want {
+		t.Errorf("cyclomatic.for = %v, want %v", got, want)
+	}
+	if got, want := sources["embed.byte"], DefaultSource+"=0"; got != want {
+		t.Errorf("embed.byte = %v, want %v", got, want)
+	}
+	if len(sources) != len(Fields(&loaded.Config)) {
+		t.Errorf("Values() has %v values, want %v", len(sources), len(Fields(&loaded.Config)))
+	}
+
+	if _, err := Resolve(filepath.Join(dir, "missing.xml"), dir); err == nil {
+		t.Error("Resolve() should fail for missing explicit config")
+	}
+}
+
+func TestCache(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	var c Cache
+
+	resolve := func() *Loaded {
+		t.Helper()
+		loaded, err := c.Resolve(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loaded
+	}
+	if first := resolve(); first.Path != "" || resolve() != first {
+		t.Errorf("Resolve() = %+v, want the same defaults", first)
+	}
+
+	path := filepath.Join(dir, ".gokys.yaml")
+	writeFile(t, path, "cyclomatic:\n  if: 7\n")
+	created := resolve()
+	if created.Path != path || created.Config.CycloComp.If != 7 || resolve() != created {
+		t.Errorf("Resolve() = %+v, want new project config read once", created)
+	}
+
+	writeFile(t, path, "cyclomatic:\n  if: 10\n")
+	if changed := resolve(); changed.Config.CycloComp.If != 10 {
+		t.Errorf("Resolve() if = %v after change, want 10", changed.Config.CycloComp.If)
+	}
+
+	writeFile(t, path, "cyclomatic:\n  iff: 10\n")
+	if _, err := c.Resolve(dir); err == nil {
+		t.Error("Resolve() should fail for invalid config")
+	}
+
+	explicit := filepath.Join(dir, "explicit.json")
+	writeFile(t, explicit, `{"halstead": 3}`)
+	c = Cache{Path: explicit}
+	if loaded := resolve(); loaded.Path != explicit || loaded.Config.Halstead != 3 {
+		t.Errorf("Resolve() = %+v, want explicit config", loaded)
+	}
+}
+
+func TestParse(t *testing.T) {
+	loaded, err := Parse("upload.yaml", []byte("cyclomatic:\n  if: 5\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Config.CycloComp.If != 5 || loaded.Config.CycloComp.For != 2 || len(loaded.Warnings) == 0 {
+		t.Errorf("Parse() = %+v, want if from data and other weights from defaults", loaded)
+	}
+
+	_, err = Parse("upload.yaml", []byte("cyclomatic:\n  iff: 5\n"))
+	if errs, ok := err.(Errors); !ok || len(errs) != 1 || errs[0].Key.Line != 2 {
+		t.Errorf("Parse() error = %v, want unknown key on line 2", err)
+	}
+}
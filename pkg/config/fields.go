@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Single weight of configuration
+type Field struct {
+	// Dot separated path of element names, e.g. `cyclomatic.if`
+	Path string
+	// Settable value of weight
+	Value reflect.Value
+}
+
+// Returns all weights of configuration in order of declaration
+func Fields(cfg *wmfp.Config) []Field {
+	return appendFields(nil, "", reflect.ValueOf(cfg).Elem())
+}
+
+func appendFields(fields []Field, prefix string, v reflect.Value) []Field {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("xml"), ",")[0]
+		path := prefix + name
+		switch {
+		case f.Anonymous:
+			// embedded weights are inlined into parent element
+			fields = appendFields(fields, prefix, v.Field(i))
+		case f.Type.Kind() == reflect.Struct:
+			fields = appendFields(fields, path+".", v.Field(i))
+		default:
+			fields = append(fields, Field{Path: path, Value: v.Field(i)})
+		}
+	}
+	return fields
+}
+
+// Returns weight formatted as it would be written in config file
+func (f Field) String() string { return fmt.Sprint(f.Value.Interface()) }
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Value set in configuration file
+type Key struct {
+	// Dot separated path of element names, e.g. `cyclomatic.if`
+	Path string
+	// Line of key in file starting from 1, 0 if unknown
+	Line int
+	// Column of key in file starting from 1, 0 if unknown
+	Column int
+}
+
+// Returns keys of all values (not sections) written in configuration file
+func Keys(data []byte, format Format) ([]Key, error) {
+	switch format {
+	case XML:
+		return xmlKeys(data)
+	case YAML:
+		return yamlKeys(data)
+	case JSON:
+		return jsonKeys(data)
+	case TOML:
+		return tomlKeys(data)
+	}
+	return nil, fmt.Errorf("unknown config format %q", format)
+}
+
+// Converts byte offset to line and column
+func position(data []byte, offset int64) (line, column int) {
+	before := data[:offset]
+	line = bytes.Count(before, []byte{'\n'}) + 1
+	column = int(offset) - bytes.LastIndexByte(before, '\n')
+	return
+}
+
+func xmlKeys(data []byte) ([]Key, error) {
+	type element struct {
+		name     string
+		offset   int64
+		children bool
+	}
+
+	var keys []Key
+	var stack []element
+	// repeated elements like `overlay` are numbered in paths
+	repeated := make(map[string]int)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return keys, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch tok.(type) {
+		case xml.StartElement:
+			if len(stack) > 0 {
+				stack[len(stack)-1].children = true
+			}
+			name := tok.(xml.StartElement).Name.Local
+			if len(stack) == 1 && name == OverlayKey {
+				name = fmt.Sprintf("%s.%d", name, repeated[name])
+				repeated[OverlayKey]++
+			}
+			stack = append(stack, element{name: name, offset: offset})
+		case xml.EndElement:
+			top := stack[len(stack)-1]
+			// root element is not a part of path
+			if !top.children && len(stack) > 1 {
+				path := make([]string, 0, len(stack)-1)
+				for _, e := range stack[1:] {
+					path = append(path, e.name)
+				}
+				line, column := position(data, top.offset)
+				keys = append(keys, Key{strings.Join(path, "."), line, column})
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+func yamlKeys(data []byte) ([]Key, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	return appendYAMLKeys(nil, "", doc.Content[0]), nil
+}
+
+func appendYAMLKeys(keys []Key, prefix string, node *yaml.Node) []Key {
+	if node.Kind != yaml.MappingNode {
+		return keys
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		switch value.Kind {
+		case yaml.MappingNode:
+			keys = appendYAMLKeys(keys, prefix+key.Value+".", value)
+		case yaml.SequenceNode:
+			for j, item := range value.Content {
+				path := fmt.Sprintf("%s%s.%d", prefix, key.Value, j)
+				if item.Kind == yaml.MappingNode {
+					keys = appendYAMLKeys(keys, path+".", item)
+				} else {
+					keys = append(keys, Key{path, item.Line, item.Column})
+				}
+			}
+		default:
+			keys = append(keys, Key{prefix + key.Value, key.Line, key.Column})
+		}
+	}
+	return keys
+}
+
+func jsonKeys(data []byte) ([]Key, error) {
+	// object or array which is being read
+	type frame struct {
+		array bool
+		// current key of object
+		key string
+		// current index of array
+		index     int
+		offset    int64
+		expectKey bool
+	}
+
+	var keys []Key
+	var stack []*frame
+	dec := json.NewDecoder(bytes.NewReader(data))
+	path := func() string {
+		names := make([]string, 0, len(stack))
+		for _, f := range stack {
+			if f.array {
+				names = append(names, strconv.Itoa(f.index))
+			} else {
+				names = append(names, f.key)
+			}
+		}
+		return strings.Join(names, ".")
+	}
+	addKey := func() {
+		line, column := position(data, stack[len(stack)-1].offset)
+		keys = append(keys, Key{path(), line, column})
+	}
+	// marks value of current key or array item as read
+	valueRead := func() {
+		if len(stack) == 0 {
+			return
+		}
+		top := stack[len(stack)-1]
+		if top.array {
+			top.index++
+		} else {
+			top.expectKey = true
+		}
+	}
+
+	for {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return keys, nil
+		} else if err != nil {
+			return nil, err
+		}
+		// offset points right after previous token
+		offset += int64(len(data[offset:]) - len(bytes.TrimLeft(data[offset:], " \t\r\n,:")))
+
+		if len(stack) > 0 && stack[len(stack)-1].array {
+			stack[len(stack)-1].offset = offset
+		}
+		switch v := tok.(type) {
+		case json.Delim:
+			switch v {
+			case '{', '[':
+				stack = append(stack, &frame{array: v == '[', expectKey: true})
+			default:
+				stack = stack[:len(stack)-1]
+				valueRead()
+			}
+		default:
+			if len(stack) == 0 {
+				continue
+			}
+			top := stack[len(stack)-1]
+			if !top.array && top.expectKey {
+				top.key = v.(string)
+				top.offset = offset
+				top.expectKey = false
+				continue
+			}
+			addKey()
+			valueRead()
+		}
+	}
+}
+
+func tomlKeys(data []byte) ([]Key, error) {
+	var values map[string]interface{}
+	md, err := toml.Decode(string(data), &values)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := tomlPositions(data)
+	var keys []Key
+	// arrays of tables like `[[overlay]]` are numbered in paths
+	arrays := make(map[string]int)
+	for _, k := range md.Keys() {
+		switch md.Type(k...) {
+		case "Hash":
+			continue
+		case "ArrayHash":
+			arrays[k[0]]++
+			continue
+		}
+		path := strings.Join(k, ".")
+		if n, ok := arrays[k[0]]; ok && len(k) > 1 {
+			path = fmt.Sprintf("%s.%d.%s", k[0], n-1, strings.Join(k[1:], "."))
+		}
+		pos := positions[path]
+		keys = append(keys, Key{path, pos.Line, pos.Column})
+	}
+	return keys, nil
+}
+
+// Finds positions of simple `key = value` lines in tables, toml decoder does
+// not report them
+func tomlPositions(data []byte) map[string]Key {
+	positions := make(map[string]Key)
+	table := ""
+	arrays := make(map[string]int)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		trimmed := strings.TrimSpace(text)
+		column := len(text) - len(strings.TrimLeft(text, " \t")) + 1
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			end := strings.Index(trimmed, "]")
+			if end <= 0 {
+				continue
+			}
+			name := strings.TrimSpace(strings.Trim(trimmed[:end], "["))
+			if strings.HasPrefix(trimmed, "[[") {
+				arrays[name]++
+			}
+			parts := strings.SplitN(name, ".", 2)
+			if n, ok := arrays[parts[0]]; ok {
+				parts[0] = fmt.Sprintf("%s.%d", parts[0], n-1)
+			}
+			table = strings.Join(parts, ".") + "."
+		case strings.Contains(trimmed, "=") && !strings.HasPrefix(trimmed, "#"):
+			key := strings.TrimSpace(trimmed[:strings.Index(trimmed, "=")])
+			key = strings.Trim(key, `"'`)
+			positions[table+key] = Key{table + key, line, column}
+		}
+	}
+	return positions
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestKeys(t *testing.T) {
+	want := []Key{{"halstead", 2, 3}, {"cyclomatic.if", 4, 5}, {"embed.sql.line", 7, 7}}
+	tests := []struct {
+		format Format
+		data   string
+	}{
+		{XML, `<config>
+  <halstead>0.1</halstead>
+  <cyclomatic>
+    <if>1</if>
+  </cyclomatic>
+  <embed><sql>
+      <line>1</line>
+  </sql></embed>
+</config>`},
+		{YAML, `# comment
+  halstead: 0.1
+  cyclomatic:
+    if: 1
+  embed:
+    sql:
+      line: 1
+`},
+		{JSON, `{
+  "halstead": 0.1,
+  "cyclomatic": {
+    "if": 1
+  },
+  "embed": {"sql": {
+      "line": 1, "ignored": [1, {"a": 2}]
+  }}
+}`},
+		{TOML, `# comment
+  halstead = 0.1
+  [cyclomatic]
+    if = 1
+  [embed]
+  [embed.sql]
+      line = 1
+`},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			got, err := Keys([]byte(tt.data), tt.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.format == JSON {
+				// array items are numbered
+				want := append(append([]Key{}, want...), Key{"embed.sql.ignored.0", 7, 30}, Key{"embed.sql.ignored.1.a", 7, 34})
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("Keys() = %v, want %v", got, want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Keys() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestKeysErrors(t *testing.T) {
+	tests := map[Format]string{
+		XML:  "<config><if>",
+		YAML: "a: [",
+		JSON: `{"a": }`,
+		TOML: "a = = 1",
+	}
+	for format, data := range tests {
+		if _, err := Keys([]byte(data), format); err == nil {
+			t.Errorf("Keys(%v, %q) should fail", format, data)
+		}
+	}
+}
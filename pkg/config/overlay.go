@@ -0,0 +1,299 @@
+package config
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Name of repeated element with overlays in config files
+const OverlayKey = "overlay"
+
+// Weights applied to files matching pattern on top of base configuration
+type Overlay struct {
+	// Glob of slash separated paths relative to directory of config file.
+	// `**` matches any number of directories, patterns without slashes match
+	// file names, patterns matching a directory apply to all files inside it.
+	// Package patterns like `./internal/...` are accepted as well
+	Pattern string
+	// Keys set by overlay, paths are relative to overlay (e.g. `cyclomatic.if`)
+	Keys []Key
+	// Values of keys set by overlay
+	values map[string]reflect.Value
+	match  *regexp.Regexp
+}
+
+// Returns configuration for file at `file`: base configuration with all
+// matching overlays applied in order of declaration
+func (l *Loaded) For(file string) wmfp.Config {
+	if len(l.Overlays) == 0 {
+		return l.Config
+	}
+
+	rel := filepath.ToSlash(file)
+	if l.Path != "" {
+		abs, err := filepath.Abs(file)
+		dir, dirErr := filepath.Abs(filepath.Dir(l.Path))
+		if err == nil && dirErr == nil {
+			if r, err := filepath.Rel(dir, abs); err == nil && !strings.HasPrefix(r, "..") {
+				rel = filepath.ToSlash(r)
+			}
+		}
+	}
+
+	return l.ForRelative(rel)
+}
+
+// Returns configuration for file at slash separated path relative to directory
+// of config file, e.g. for files which are not on disk
+func (l *Loaded) ForRelative(rel string) wmfp.Config {
+	cfg := l.Config
+	for i := range l.Overlays {
+		if l.Overlays[i].Matches(rel) {
+			l.Overlays[i].apply(&cfg)
+		}
+	}
+	return cfg
+}
+
+// Checks if overlay applies to slash separated path
+func (o *Overlay) Matches(file string) bool {
+	file = strings.TrimPrefix(path.Clean(file), "./")
+	if !strings.Contains(strings.TrimSuffix(o.Pattern, "/"), "/") {
+		return o.match.MatchString(path.Base(file))
+	}
+	for p := file; p != "." && p != "/"; p = path.Dir(p) {
+		if o.match.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *Overlay) apply(cfg *wmfp.Config) {
+	for _, field := range Fields(cfg) {
+		if value, ok := o.values[field.Path]; ok {
+			field.Value.Set(value)
+		}
+	}
+}
+
+// Encodes base configuration and overlays in given format, overlays keep only
+// weights set by them
+func (l *Loaded) Encode(format Format) ([]byte, error) {
+	doc := document{Config: l.Config}
+	for i := range l.Overlays {
+		doc.Overlays = append(doc.Overlays, l.Overlays[i].value())
+	}
+	return encode(&doc, format)
+}
+
+// Returns struct with pattern and weights set by overlay, it has the same
+// tags as configuration, so it is encoded like a partial config
+func (o *Overlay) value() interface{} {
+	var cfg wmfp.Config
+	o.apply(&cfg)
+	fields := []reflect.StructField{{
+		Name: "Pattern",
+		Type: reflect.TypeOf(""),
+		Tag:  `xml:"pattern,attr" yaml:"pattern" json:"pattern" toml:"pattern"`,
+	}}
+	values := []reflect.Value{reflect.ValueOf(o.Pattern)}
+	fields, values = appendSet(fields, values, "", reflect.ValueOf(cfg), o.values)
+	return newStruct(fields, values).Interface()
+}
+
+// Appends fields of struct `v` with paths in `set` and structs containing them
+func appendSet(fields []reflect.StructField, values []reflect.Value, prefix string, v reflect.Value, set map[string]reflect.Value) ([]reflect.StructField, []reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("xml"), ",")[0]
+		value := v.Field(i)
+		switch {
+		case f.Anonymous:
+			// embedded weights are inlined into parent element
+			fields, values = appendSet(fields, values, prefix, value, set)
+			continue
+		case f.Type.Kind() == reflect.Struct:
+			inner, innerValues := appendSet(nil, nil, prefix+name+".", value, set)
+			if len(inner) == 0 {
+				continue
+			}
+			value = newStruct(inner, innerValues)
+		default:
+			if _, ok := set[prefix+name]; !ok {
+				continue
+			}
+		}
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: value.Type(), Tag: f.Tag})
+		values = append(values, value)
+	}
+	return fields, values
+}
+
+func newStruct(fields []reflect.StructField, values []reflect.Value) reflect.Value {
+	v := reflect.New(reflect.StructOf(fields)).Elem()
+	for i, value := range values {
+		v.Field(i).Set(value)
+	}
+	return v
+}
+
+// Converts glob pattern to regular expression
+func compilePattern(pattern string) *regexp.Regexp {
+	pattern = strings.TrimPrefix(pattern, "./")
+	pattern = strings.TrimSuffix(pattern, "/")
+	if strings.HasSuffix(pattern, "/...") || pattern == "..." {
+		pattern = strings.TrimSuffix(pattern, "...") + "**"
+	}
+
+	var re strings.Builder
+	re.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			re.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			re.WriteString(".*")
+			i++
+		case c == '*':
+			re.WriteString("[^/]*")
+		case c == '?':
+			re.WriteString("[^/]")
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	re.WriteString("$")
+	return regexp.MustCompile(re.String())
+}
+
+// Decodes overlays of config file, `keys` are keys of the whole file
+func decodeOverlays(data []byte, format Format, keys []Key) ([]Overlay, error) {
+	var patterns []string
+	var decoders []func(*wmfp.Config) error
+
+	switch format {
+	case XML:
+		var doc struct {
+			Overlays []struct {
+				Pattern string `xml:"pattern,attr"`
+				Inner   []byte `xml:",innerxml"`
+			} `xml:"overlay"`
+		}
+		if err := xml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for _, o := range doc.Overlays {
+			inner := append(append([]byte("<config>"), o.Inner...), "</config>"...)
+			patterns = append(patterns, o.Pattern)
+			decoders = append(decoders, func(cfg *wmfp.Config) error { return Decode(inner, XML, cfg) })
+		}
+	case YAML:
+		var doc struct {
+			Overlays []yaml.Node `yaml:"overlay"`
+		}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for i := range doc.Overlays {
+			node := &doc.Overlays[i]
+			var p struct {
+				Pattern string `yaml:"pattern"`
+			}
+			if err := node.Decode(&p); err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, p.Pattern)
+			decoders = append(decoders, func(cfg *wmfp.Config) error { return node.Decode(cfg) })
+		}
+	case JSON:
+		var doc struct {
+			Overlays []json.RawMessage `json:"overlay"`
+		}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		for _, raw := range doc.Overlays {
+			raw := raw
+			var p struct {
+				Pattern string `json:"pattern"`
+			}
+			if err := json.Unmarshal(raw, &p); err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, p.Pattern)
+			decoders = append(decoders, func(cfg *wmfp.Config) error { return json.Unmarshal(raw, cfg) })
+		}
+	case TOML:
+		var doc struct {
+			Overlays []toml.Primitive `toml:"overlay"`
+		}
+		md, err := toml.Decode(string(data), &doc)
+		if err != nil {
+			return nil, err
+		}
+		for _, prim := range doc.Overlays {
+			prim := prim
+			var p struct {
+				Pattern string `toml:"pattern"`
+			}
+			if err := md.PrimitiveDecode(prim, &p); err != nil {
+				return nil, err
+			}
+			patterns = append(patterns, p.Pattern)
+			decoders = append(decoders, func(cfg *wmfp.Config) error { return md.PrimitiveDecode(prim, cfg) })
+		}
+	}
+
+	overlays := make([]Overlay, len(patterns))
+	for i, pattern := range patterns {
+		o := &overlays[i]
+		o.Pattern = pattern
+
+		prefix := OverlayKey + "." + strconv.Itoa(i) + "."
+		for _, key := range keys {
+			if strings.HasPrefix(key.Path, prefix) {
+				key.Path = strings.TrimPrefix(key.Path, prefix)
+				o.Keys = append(o.Keys, key)
+			}
+		}
+
+		var cfg wmfp.Config
+		if err := decoders[i](&cfg); err != nil {
+			return nil, fmt.Errorf("overlay %q: %w", pattern, err)
+		}
+		o.values = make(map[string]reflect.Value)
+		for _, field := range Fields(&cfg) {
+			o.values[field.Path] = field.Value
+		}
+		// only values set by overlay are applied
+		set := make(map[string]bool)
+		for _, key := range o.Keys {
+			set[key.Path] = true
+		}
+		for p := range o.values {
+			if !set[p] {
+				delete(o.values, p)
+			}
+		}
+		o.match = compilePattern(pattern)
+	}
+	return overlays, nil
+}
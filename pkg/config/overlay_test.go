@@ -0,0 +1,194 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestOverlayMatches(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"*_test.go", "main_test.go", true},
+		{"*_test.go", "pkg/a/a_test.go", true},
+		{"*_test.go", "pkg/a/a.go", false},
+		{"internal/legacy/**", "internal/legacy/a.go", true},
+		{"internal/legacy/**", "internal/legacy/x/y/a.go", true},
+		{"internal/legacy/**", "internal/new/a.go", false},
+		{"internal/legacy", "internal/legacy/a.go", true},
+		{"internal/legacy", "internal/legacy2/a.go", false},
+		{"./internal/...", "internal/x/a.go", true},
+		{"./internal/...", "cmd/a.go", false},
+		{"**/gen/*.go", "gen/a.go", true},
+		{"**/gen/*.go", "pkg/gen/a.go", true},
+		{"**/gen/*.go", "pkg/gen/x/a.go", false},
+		{"pkg/*/a.go", "pkg/x/a.go", true},
+		{"pkg/*/a.go", "pkg/x/y/a.go", false},
+		{"pkg/?.go", "pkg/a.go", true},
+	}
+	for _, tt := range tests {
+		o := Overlay{Pattern: tt.pattern, match: compilePattern(tt.pattern)}
+		if got := o.Matches(tt.file); got != tt.want {
+			t.Errorf("Overlay{%q}.Matches(%q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
+
+func TestLoadedFor(t *testing.T) {
+	configs := map[string]string{
+		".gokys.xml": `<config>
+  <halstead>0.5</halstead>
+  <overlay pattern="*_test.go">
+    <cyclomatic><if>0.5</if></cyclomatic>
+  </overlay>
+  <overlay pattern="legacy/**">
+    <halstead>1</halstead>
+    <cyclomatic><if>3</if></cyclomatic>
+  </overlay>
+</config>`,
+		".gokys.yaml": `halstead: 0.5
+overlay:
+  - pattern: "*_test.go"
+    cyclomatic: {if: 0.5}
+  - pattern: legacy/**
+    halstead: 1
+    cyclomatic: {if: 3}
+`,
+		".gokys.json": `{"halstead": 0.5, "overlay": [
+  {"pattern": "*_test.go", "cyclomatic": {"if": 0.5}},
+  {"pattern": "legacy/**", "halstead": 1, "cyclomatic": {"if": 3}}
+]}`,
+		".gokys.toml": `halstead = 0.5
+
+[[overlay]]
+pattern = "*_test.go"
+[overlay.cyclomatic]
+if = 0.5
+
+[[overlay]]
+pattern = "legacy/**"
+halstead = 1
+[overlay.cyclomatic]
+if = 3
+`,
+	}
+	for name, content := range configs {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, name)
+			writeFile(t, path, content)
+
+			loaded, err := LoadWithDefaults(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(loaded.Overlays) != 2 {
+				t.Fatalf("got %d overlays, want 2", len(loaded.Overlays))
+			}
+			def := Default()
+
+			tests := []struct {
+				file     string
+				halstead float64
+				cycloIf  float64
+			}{
+				{"main.go", 0.5, def.CycloComp.If},
+				{"main_test.go", 0.5, 0.5},
+				{"legacy/a/a.go", 1, 3},
+				// later overlays override earlier ones
+				{"legacy/a_test.go", 1, 3},
+			}
+			for _, tt := range tests {
+				cfg := loaded.For(filepath.Join(dir, tt.file))
+				if cfg.Halstead != tt.halstead || cfg.CycloComp.If != tt.cycloIf {
+					t.Errorf("For(%q) halstead = %v, if = %v, want %v, %v",
+						tt.file, cfg.Halstead, cfg.CycloComp.If, tt.halstead, tt.cycloIf)
+				}
+				if cfg.CycloComp.For != def.CycloComp.For {
+					t.Errorf("For(%q) changed weight not set by overlay", tt.file)
+				}
+			}
+			if loaded.Config.CycloComp.If != def.CycloComp.If {
+				t.Errorf("overlay changed base config")
+			}
+		})
+	}
+}
+
+func TestLoadedEncode(t *testing.T) {
+	loaded, err := Parse("config.yaml", []byte(`halstead: 0.5
+overlay:
+  - pattern: "*_test.go"
+    cyclomatic: {if: 0.5}
+  - pattern: legacy/**
+    halstead: 1
+    embed: {line: 2, sql: {byte: 3}}
+  - pattern: empty/**
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{"main.go", "main_test.go", "legacy/a.go", "legacy/a_test.go", "empty/a.go"}
+	for _, format := range []Format{XML, YAML, JSON, TOML} {
+		t.Run(string(format), func(t *testing.T) {
+			data, err := loaded.Encode(format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Parse("config."+string(format), data)
+			if err != nil {
+				t.Fatalf("Parse(Encode()) error = %v, data:\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got.Config, loaded.Config) {
+				t.Errorf("base config = %+v, want %+v", got.Config, loaded.Config)
+			}
+			if len(got.Overlays) != len(loaded.Overlays) {
+				t.Fatalf("got %d overlays, want %d, data:\n%s", len(got.Overlays), len(loaded.Overlays), data)
+			}
+			for i, o := range got.Overlays {
+				want := &loaded.Overlays[i]
+				if o.Pattern != want.Pattern || len(o.values) != len(want.values) {
+					t.Errorf("overlay %d = %q with %d weights, want %q with %d weights",
+						i, o.Pattern, len(o.values), want.Pattern, len(want.values))
+				}
+			}
+			for _, file := range files {
+				if got, want := got.ForRelative(file), loaded.ForRelative(file); !reflect.DeepEqual(got, want) {
+					t.Errorf("ForRelative(%q) = %+v, want %+v", file, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateOverlays(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	writeFile(t, path, `overlay:
+  - cyclomatic: {if: 1}
+  - pattern: "*.go"
+    cyclomatic: {iff: 1, for: -1}
+`)
+	_, err := LoadWithDefaults(path)
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("LoadWithDefaults() error = %v, want Errors", err)
+	}
+	want := []string{
+		path + ":2:18: overlay 0 has no pattern",
+		path + `:4:18: unknown key "overlay.1.cyclomatic.iff"`,
+		path + `:4:26: weight "overlay.1.cyclomatic.for" should be a non-negative number, got -1`,
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("got errors %v, want %v", errs, want)
+	}
+	for i := range want {
+		if errs[i].Error() != want[i] {
+			t.Errorf("error %d = %q, want %q", i, errs[i].Error(), want[i])
+		}
+	}
+}
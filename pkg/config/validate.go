@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Problem found in configuration file
+type Issue struct {
+	// Path to config file
+	File string
+	// Key with problem, position is unknown for missing keys
+	Key Key
+	// Description of problem
+	Message string
+}
+
+// Formats issue as `file:line:column: message`
+func (i Issue) Error() string {
+	if i.Key.Line == 0 {
+		return fmt.Sprintf("%s: %s", i.File, i.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", i.File, i.Key.Line, i.Key.Column, i.Message)
+}
+
+// Issues which make configuration invalid
+type Errors []Issue
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, issue := range e {
+		messages[i] = issue.Error()
+	}
+	return strings.Join(messages, "\n")
+}
+
+// Checks keys set in config file `path` and values decoded from them. Unknown
+// keys and negative, infinite or NaN weights are errors, missing weights are
+// returned as warnings
+func Validate(path string, keys []Key, cfg *wmfp.Config) (warnings []Issue, err error) {
+	fields := make(map[string]reflect.Value)
+	for _, field := range Fields(cfg) {
+		fields[field.Path] = field.Value
+	}
+
+	var errs Errors
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		// overlays are checked by `ValidateOverlays`
+		if key.Path == OverlayKey || strings.HasPrefix(key.Path, OverlayKey+".") {
+			continue
+		}
+		set[key.Path] = true
+		errs = append(errs, checkKey(path, key, key.Path, fields)...)
+	}
+
+	for _, field := range Fields(cfg) {
+		if !set[field.Path] {
+			message := fmt.Sprintf("missing weight %q, using default %v", field.Path, field.String())
+			warnings = append(warnings, Issue{path, Key{Path: field.Path}, message})
+		}
+	}
+
+	if len(errs) > 0 {
+		return warnings, errs
+	}
+	return warnings, nil
+}
+
+// Checks overlays of config file `path`: each overlay needs a pattern
+// and may only set known weights to non-negative numbers
+func ValidateOverlays(path string, overlays []Overlay) error {
+	var errs Errors
+	for i, o := range overlays {
+		prefix := fmt.Sprintf("%s.%d.", OverlayKey, i)
+		at := Key{Path: strings.TrimSuffix(prefix, ".")}
+		if len(o.Keys) > 0 {
+			at.Line, at.Column = o.Keys[0].Line, o.Keys[0].Column
+		}
+		if o.Pattern == "" {
+			errs = append(errs, Issue{path, at, fmt.Sprintf("overlay %d has no pattern", i)})
+		}
+
+		fields := make(map[string]reflect.Value, len(o.values))
+		for p, value := range o.values {
+			fields[p] = value
+		}
+		for _, key := range o.Keys {
+			if key.Path == "pattern" {
+				continue
+			}
+			key.Path = prefix + key.Path
+			errs = append(errs, checkKey(path, key, strings.TrimPrefix(key.Path, prefix), fields)...)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// Checks that key set in config file `path` is known and has valid value,
+// `name` is path of key in `fields`
+func checkKey(path string, key Key, name string, fields map[string]reflect.Value) []Issue {
+	value, ok := fields[name]
+	if !ok {
+		return []Issue{{path, key, fmt.Sprintf("unknown key %q", key.Path)}}
+	}
+	if value.Kind() != reflect.Float64 {
+		return nil
+	}
+	if f := value.Float(); math.IsNaN(f) || math.IsInf(f, 0) || f < 0 {
+		return []Issue{{path, key, fmt.Sprintf("weight %q should be a non-negative number, got %v", key.Path, f)}}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Default()
+	total := len(Fields(&cfg))
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		errors   []string
+		warnings int
+	}{
+		{"Valid", "a.yaml", "cyclomatic:\n  if: 1\n", nil, total - 1},
+		{"Typo", "b.xml", "<config>\n  <codestruct>\n    <interfce>1</interfce>\n  </codestruct>\n</config>",
+			[]string{`b.xml:3:5: unknown key "codestruct.interfce"`}, total},
+		{"Negative", "c.json", "{\n  \"halstead\": -1\n}",
+			[]string{`c.json:2:3: weight "halstead" should be a non-negative number, got -1`}, 0},
+		{"NaN", "d.toml", "[inline]\nint = nan\nfloat = inf\n",
+			[]string{`d.toml:2:1: weight "inline.int" should be a non-negative number, got NaN`,
+				`d.toml:3:1: weight "inline.float" should be a non-negative number, got +Inf`}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+			writeFile(t, path, tt.content)
+
+			loaded, err := LoadWithDefaults(path)
+			if tt.errors == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(loaded.Warnings) != tt.warnings {
+					t.Errorf("len(Warnings) = %v, want %v", len(loaded.Warnings), tt.warnings)
+				}
+				return
+			}
+
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("LoadWithDefaults() error = %v, want validation errors", err)
+			}
+			got := strings.Split(errs.Error(), "\n")
+			for i := range tt.errors {
+				tt.errors[i] = filepath.Join(dir, tt.errors[i])
+			}
+			if !reflect.DeepEqual(got, tt.errors) {
+				t.Errorf("errors = %q, want %q", got, tt.errors)
+			}
+		})
+	}
+}
+
+func TestIssueError(t *testing.T) {
+	issue := Issue{"a.xml", Key{Path: "if"}, "missing"}
+	if got := issue.Error(); got != "a.xml: missing" {
+		t.Errorf("Error() = %v, want a.xml: missing", got)
+	}
+}
@@ -9,65 +9,101 @@ import (
 // Config for metric with various weights for syntactical structures
 type Weights struct {
 	// If weight
-	If float64 `xml:"if"`
+	If float64 `xml:"if" yaml:"if" json:"if" toml:"if"`
 	// For weight
-	For float64 `xml:"for"`
+	For float64 `xml:"for" yaml:"for" json:"for" toml:"for"`
 	// Range weight
-	Rng float64 `xml:"rng"`
+	Rng float64 `xml:"rng" yaml:"rng" json:"rng" toml:"rng"`
 	// Case weight
-	Case float64 `xml:"case"`
+	Case float64 `xml:"case" yaml:"case" json:"case" toml:"case"`
 	// Boolean and weight
-	And float64 `xml:"and"`
+	And float64 `xml:"and" yaml:"and" json:"and" toml:"and"`
 	// Boolean or weight
-	Or float64 `xml:"or"`
+	Or float64 `xml:"or" yaml:"or" json:"or" toml:"or"`
+}
+
+// Raw counts of branching constructs, every field except `Funcs` counts what
+// weight with the same name is multiplied by
+type Counts struct {
+	// Functions, each of them adds base complexity 1
+	Funcs float64 `json:"funcs"`
+	// Number of ifs
+	If float64 `json:"if"`
+	// Number of for loops
+	For float64 `json:"for"`
+	// Number of range loops
+	Rng float64 `json:"rng"`
+	// Number of switch and select cases
+	Case float64 `json:"case"`
+	// Number of boolean ands
+	And float64 `json:"and"`
+	// Number of boolean ors
+	Or float64 `json:"or"`
+}
+
+// Computes weighted complexity from counts
+func (c Counts) Score(config *Weights) float64 {
+	return c.Funcs + c.If*config.If + c.For*config.For + c.Rng*config.Rng +
+		c.Case*config.Case + c.And*config.And + c.Or*config.Or
 }
 
 // Intermidiate state of metric
 type Metric struct {
 	// Config with weights
 	Config Weights
-	comp   float64
+	counts Counts
+	// function declaration which is parsed, nodes outside of it are skipped
+	fn *ast.FuncDecl
+}
+
+// Parses ast node and collects all of its metrics. Every function declaration
+// adds base complexity, decision points inside it add their weights, so
+// complexity of any part of a function can be attributed to its nodes.
+// Decision points outside of function declarations (e.g. in function literals
+// of package level variables) are not counted
+func (m *Metric) ParseNode(n ast.Node) { m.count(n, &m.counts) }
+
+// Parses node like `ParseNode`, but returns counts of node instead of
+// collecting them
+func (m *Metric) Count(n ast.Node) (c Counts) {
+	m.count(n, &c)
+	return
 }
 
-// Parses ast node and collects all of its metrics
-func (m *Metric) ParseNode(n ast.Node) {
-	if v, ok := n.(*ast.FuncDecl); ok {
-		m.comp += getCycloComp(v, &m.Config)
+func (m *Metric) count(n ast.Node, c *Counts) {
+	if fd, ok := n.(*ast.FuncDecl); ok {
+		m.fn = fd
+	}
+	// declarations are parsed before their children
+	if n == nil || m.fn == nil || n.Pos() < m.fn.Pos() || n.End() > m.fn.End() {
+		return
 	}
+	countNode(n, c)
 }
 
 // Returns final score
-func (m Metric) Finish() float64 { return m.comp }
-
-type branchVisitor func(n ast.Node) (w ast.Visitor)
+func (m Metric) Finish() float64 { return m.counts.Score(&m.Config) }
 
-func (v branchVisitor) Visit(n ast.Node) (w ast.Visitor) {
-	return v(n)
-}
+// Returns raw counts collected so far
+func (m Metric) Counts() Counts { return m.counts }
 
-func getCycloComp(fd *ast.FuncDecl, config *Weights) float64 {
-	var comp float64 = 1
-	var v ast.Visitor
-	v = branchVisitor(func(n ast.Node) (w ast.Visitor) {
-		switch n := n.(type) {
-		case *ast.IfStmt:
-			comp += config.If
-		case *ast.ForStmt:
-			comp += config.For
-		case *ast.RangeStmt:
-			comp += config.Rng
-		case *ast.CaseClause, *ast.CommClause:
-			comp += config.Case
-		case *ast.BinaryExpr:
-			if n.Op == token.LAND {
-				comp += config.And
-			} else if n.Op == token.LOR {
-				comp += config.Or
-			}
+func countNode(n ast.Node, c *Counts) {
+	switch n := n.(type) {
+	case *ast.FuncDecl:
+		c.Funcs++
+	case *ast.IfStmt:
+		c.If++
+	case *ast.ForStmt:
+		c.For++
+	case *ast.RangeStmt:
+		c.Rng++
+	case *ast.CaseClause, *ast.CommClause:
+		c.Case++
+	case *ast.BinaryExpr:
+		if n.Op == token.LAND {
+			c.And++
+		} else if n.Op == token.LOR {
+			c.Or++
 		}
-		return v
-	})
-	ast.Walk(v, fd)
-
-	return comp
+	}
 }
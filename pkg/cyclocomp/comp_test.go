@@ -29,18 +29,19 @@ func TestCycloComp1(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	m := Metric{Config: Weights{If: 1}}
+
 	ast.Inspect(expr, func(n ast.Node) bool {
-		switch v := n.(type) {
-		case *ast.FuncDecl:
-			want := uint(3)
-			got := uint(getCycloComp(v, &Weights{If: 1}))
-
-			if got != want {
-				t.Fatalf(`GetCycloComp("package main...") = %v, Wanted %v`, got, want)
-			}
-		}
+		m.ParseNode(n)
 		return true
 	})
+
+	want := uint(3)
+	got := uint(m.Finish())
+
+	if got != want {
+		t.Fatalf(`GetCycloComp("package main...") = %v, Wanted %v`, got, want)
+	}
 }
 
 func TestCycloComp2(t *testing.T) {
@@ -80,3 +81,31 @@ func TestCycloComp2(t *testing.T) {
 		t.Fatalf(`GetCycloComp("package main...") = %v, Wanted %v`, got, want)
 	}
 }
+
+func TestPackageLevelLiterals(t *testing.T) {
+	srcCode := `package main
+var f = func(x int) bool {
+	if x > 0 {
+		return true
+	}
+	return x < -1 || x == -5
+}
+
+func main() {}`
+	expr, err := parser.ParseFile(token.NewFileSet(), "", srcCode, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var m Metric
+	ast.Inspect(expr, func(n ast.Node) bool {
+		m.ParseNode(n)
+		return true
+	})
+
+	// only main is counted, literal outside of function declarations is not
+	want := Counts{Funcs: 1}
+	if got := m.Counts(); got != want {
+		t.Errorf("Counts() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,264 @@
+// Package with metric of documentation coverage of exported identifiers.
+package doccov
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Config for metric
+type Weights struct {
+	// Minutes of writing documentation for every undocumented exported identifier
+	Missing float64 `xml:"missing" yaml:"missing" json:"missing" toml:"missing"`
+}
+
+// Exported identifier
+type Symbol struct {
+	// One of `func`, `method`, `type`, `const` or `var`
+	Kind string
+	// Name of identifier, methods are prefixed with receiver type
+	Name string
+	// Position of identifier
+	Pos token.Pos
+	// Whether identifier has doc comment
+	Documented bool
+}
+
+// Raw counts of metric
+type Counts struct {
+	// Number of undocumented exported identifiers
+	Missing float64 `json:"missing"`
+}
+
+// Computes weighted result from counts
+func (c Counts) Score(config *Weights) float64 { return c.Missing * config.Missing }
+
+// Intermidiate state of metric
+type Metric struct {
+	// Config with weights
+	Config Weights
+	// Whether measured file is a `_test.go` file, its tests, benchmarks,
+	// examples and fuzz targets are not counted
+	TestFile bool
+	// Exported identifiers found so far
+	Symbols []Symbol
+	counts  Counts
+	// declarations inside of function bodies, they are not part of API
+	local map[*ast.GenDecl]bool
+}
+
+// Parses ast node and collects exported identifiers of top level declarations
+func (m *Metric) ParseNode(n ast.Node) { m.count(n, &m.counts) }
+
+// Parses node like `ParseNode`, but returns counts of node instead of
+// collecting them, its symbols are still collected
+func (m *Metric) Count(n ast.Node) (c Counts) {
+	m.count(n, &c)
+	return
+}
+
+func (m *Metric) count(n ast.Node, c *Counts) {
+	switch v := n.(type) {
+	case *ast.DeclStmt:
+		// statement is visited before its declaration
+		if gd, ok := v.Decl.(*ast.GenDecl); ok {
+			if m.local == nil {
+				m.local = make(map[*ast.GenDecl]bool)
+			}
+			m.local[gd] = true
+		}
+	case *ast.FuncDecl:
+		m.addFuncDecl(v, c)
+	case *ast.GenDecl:
+		if !m.local[v] {
+			m.addGenDecl(v, c)
+		}
+	}
+}
+
+// Returns effort of writing missing documentation
+func (m Metric) Finish() float64 { return m.Counts().Score(&m.Config) }
+
+// Returns raw counts collected so far
+func (m Metric) Counts() Counts { return m.counts }
+
+// Returns exported identifiers without doc comments
+func (m *Metric) Undocumented() []Symbol {
+	var result []Symbol
+	for _, s := range m.Symbols {
+		if !s.Documented {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+func (m *Metric) addFuncDecl(fd *ast.FuncDecl, c *Counts) {
+	if !fd.Name.IsExported() || m.TestFile && isTestFunc(fd) {
+		return
+	}
+	symbol := Symbol{Kind: "func", Name: fd.Name.Name, Pos: fd.Name.Pos(), Documented: fd.Doc != nil}
+	if fd.Recv != nil && len(fd.Recv.List) > 0 {
+		recv := recvType(fd.Recv.List[0].Type)
+		if recv == nil || !recv.IsExported() {
+			return
+		}
+		symbol.Kind = "method"
+		symbol.Name = recv.Name + "." + symbol.Name
+	}
+	m.add(symbol, c)
+}
+
+func (m *Metric) addGenDecl(gd *ast.GenDecl, c *Counts) {
+	for _, spec := range gd.Specs {
+		switch v := spec.(type) {
+		case *ast.TypeSpec:
+			if v.Name.IsExported() {
+				m.add(Symbol{"type", v.Name.Name, v.Name.Pos(), gd.Doc != nil || v.Doc != nil}, c)
+			}
+		case *ast.ValueSpec:
+			for _, name := range v.Names {
+				if name.IsExported() {
+					m.add(Symbol{gd.Tok.String(), name.Name, name.Pos(), gd.Doc != nil || v.Doc != nil}, c)
+				}
+			}
+		}
+	}
+}
+
+func (m *Metric) add(s Symbol, c *Counts) {
+	m.Symbols = append(m.Symbols, s)
+	if !s.Documented {
+		c.Missing++
+	}
+}
+
+// Reports whether file at path is a test file, see `Metric.TestFile`
+func IsTestFile(path string) bool { return strings.HasSuffix(path, "_test.go") }
+
+// Tests, benchmarks, examples and fuzz targets are not documented by
+// convention, they are recognized by name and signature like `go test` does
+func isTestFunc(fd *ast.FuncDecl) bool {
+	if fd.Recv != nil || fd.Type.TypeParams != nil || fd.Type.Results != nil && len(fd.Type.Results.List) > 0 {
+		return false
+	}
+	params := fd.Type.Params.List
+	switch name := fd.Name.Name; {
+	case name == "TestMain":
+		return isTestingParam(params, "M")
+	case isTestName(name, "Test"):
+		return isTestingParam(params, "T")
+	case isTestName(name, "Benchmark"):
+		return isTestingParam(params, "B")
+	case isTestName(name, "Fuzz"):
+		return isTestingParam(params, "F")
+	case isTestName(name, "Example"):
+		return len(params) == 0
+	}
+	return false
+}
+
+// Reports whether name is prefix followed by nothing or by not lower case
+// letter, e.g. `Testify` is not a test
+func isTestName(name, prefix string) bool {
+	if !strings.HasPrefix(name, prefix) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(name[len(prefix):])
+	return r == utf8.RuneError || !unicode.IsLower(r)
+}
+
+// Reports whether parameters are a single `*testing.<typ>`
+func isTestingParam(params []*ast.Field, typ string) bool {
+	if len(params) != 1 || len(params[0].Names) > 1 {
+		return false
+	}
+	star, ok := params[0].Type.(*ast.StarExpr)
+	if !ok {
+		return false
+	}
+	sel, ok := star.X.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	return ok && pkg.Name == "testing" && sel.Sel.Name == typ
+}
+
+func recvType(expr ast.Expr) *ast.Ident {
+	switch v := expr.(type) {
+	case *ast.StarExpr:
+		return recvType(v.X)
+	case *ast.IndexExpr:
+		return recvType(v.X)
+	case *ast.ParenExpr:
+		return recvType(v.X)
+	case *ast.Ident:
+		return v
+	}
+	return nil
+}
+
+// Location of undocumented identifier
+type Missing struct {
+	// Kind of identifier, see `Symbol`
+	Kind string `json:"kind"`
+	// Name of identifier
+	Name string `json:"name"`
+	// File of identifier
+	Path string `json:"path"`
+	// Line of identifier
+	Line int `json:"line"`
+}
+
+// Documentation coverage of a file or package
+type Coverage struct {
+	// Number of exported identifiers
+	Exported uint `json:"exported"`
+	// Number of documented exported identifiers
+	Documented uint `json:"documented"`
+	// Percentage of documented identifiers, 100 if nothing is exported
+	Percent float64 `json:"percent"`
+	// Undocumented identifiers
+	Undocumented []Missing `json:"undocumented,omitempty"`
+}
+
+// Builds coverage of identifiers collected from file
+func NewCoverage(fset *token.FileSet, symbols []Symbol) Coverage {
+	var c Coverage
+	for _, s := range symbols {
+		c.Exported++
+		if s.Documented {
+			c.Documented++
+			continue
+		}
+		pos := fset.Position(s.Pos)
+		c.Undocumented = append(c.Undocumented, Missing{s.Kind, s.Name, pos.Filename, pos.Line})
+	}
+	c.Percent = percent(c.Documented, c.Exported)
+	return c
+}
+
+// Combines coverages, e.g. of all files in package
+func (c Coverage) Add(other Coverage) Coverage {
+	result := Coverage{
+		Exported:     c.Exported + other.Exported,
+		Documented:   c.Documented + other.Documented,
+		Undocumented: append(append([]Missing{}, c.Undocumented...), other.Undocumented...),
+	}
+	if len(result.Undocumented) == 0 {
+		result.Undocumented = nil
+	}
+	result.Percent = percent(result.Documented, result.Exported)
+	return result
+}
+
+func percent(documented, exported uint) float64 {
+	if exported == 0 {
+		return 100
+	}
+	return float64(documented) * 100 / float64(exported)
+}
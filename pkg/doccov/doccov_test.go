@@ -0,0 +1,157 @@
+package doccov
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestMetric(t *testing.T) {
+	src := `package lib
+
+// Documented type
+type A struct{}
+
+type B int
+
+func (A) Undocumented() {}
+
+// Documented method
+func (*A) Documented() {}
+
+func (b) Hidden() {}
+
+func Exported() {}
+
+func unexported() {}
+
+func TestSomething(t *testing.T) {}
+
+// Documented group
+const (
+	C1 = 1
+	C2 = 2
+)
+
+var (
+	// Documented var
+	V1 = 1
+	V2, v3 = 2, 3
+)
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "lib_test.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := Metric{Config: Weights{Missing: 5}, TestFile: true}
+	ast.Inspect(file, func(n ast.Node) bool {
+		m.ParseNode(n)
+		return true
+	})
+
+	if got := m.Finish(); got != 20 {
+		t.Errorf("Finish() = %v, want 20", got)
+	}
+
+	got := NewCoverage(fset, m.Symbols)
+	want := Coverage{
+		Exported:   9,
+		Documented: 5,
+		Percent:    500. / 9,
+		Undocumented: []Missing{
+			{"type", "B", "lib_test.go", 6},
+			{"method", "A.Undocumented", "lib_test.go", 8},
+			{"func", "Exported", "lib_test.go", 15},
+			{"var", "V2", "lib_test.go", 30},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewCoverage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestTestFuncs(t *testing.T) {
+	tests := []struct {
+		decl     string
+		testFile bool
+		counted  bool
+	}{
+		{"func TestA(t *testing.T) {}", true, false},
+		{"func Test(t *testing.T) {}", true, false},
+		{"func TestMain(m *testing.M) {}", true, false},
+		{"func Benchmark_a(b *testing.B) {}", true, false},
+		{"func FuzzA(f *testing.F) {}", true, false},
+		{"func ExampleA() {}", true, false},
+		{"func TestA(t *testing.T) {}", false, true},
+		{"func Testify(t *testing.T) {}", true, true},
+		{"func TestA(b *testing.B) {}", true, true},
+		{"func TestA(t *testing.T) error { return nil }", true, true},
+		{"func TestA(a, b *testing.T) {}", true, true},
+		{"func ExampleA(x int) {}", true, true},
+		{"func BenchmarkA() {}", true, true},
+	}
+	for _, tt := range tests {
+		file, err := parser.ParseFile(token.NewFileSet(), "", "package p\n"+tt.decl, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := Metric{TestFile: tt.testFile}
+		ast.Inspect(file, func(n ast.Node) bool {
+			m.ParseNode(n)
+			return true
+		})
+		if counted := len(m.Symbols) == 1; counted != tt.counted {
+			t.Errorf("%s in test file %v: counted = %v, want %v", tt.decl, tt.testFile, counted, tt.counted)
+		}
+	}
+}
+
+func TestLocalDecls(t *testing.T) {
+	src := `package p
+
+// F declares local identifiers
+func F() {
+	type Local struct{}
+	const X = 1
+	var Y int
+	g := func() {
+		var Z int
+		_ = Z
+	}
+	_, _ = Y, g
+}
+
+var V int
+`
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Metric
+	ast.Inspect(file, func(n ast.Node) bool {
+		m.ParseNode(n)
+		return true
+	})
+	if len(m.Symbols) != 2 || m.Symbols[0].Name != "F" || m.Symbols[1].Name != "V" {
+		t.Errorf("Symbols = %+v, want F and V only", m.Symbols)
+	}
+}
+
+func TestCoverageAdd(t *testing.T) {
+	a := Coverage{Exported: 2, Documented: 1, Percent: 50, Undocumented: []Missing{{Name: "A"}}}
+	b := Coverage{Exported: 2, Documented: 2, Percent: 100}
+
+	got := a.Add(b)
+	want := Coverage{Exported: 4, Documented: 3, Percent: 75, Undocumented: []Missing{{Name: "A"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Add() = %+v, want %+v", got, want)
+	}
+
+	if got := (Coverage{}).Add(Coverage{}); got.Percent != 100 || got.Undocumented != nil {
+		t.Errorf("empty Add() = %+v, want 100%% without undocumented", got)
+	}
+}
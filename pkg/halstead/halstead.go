@@ -11,6 +11,8 @@ import (
 type Metric struct {
 	operators map[token.Token]uint
 	operands  map[string]uint
+	// total number of operators and operands
+	length uint
 }
 
 // Constructor of metric
@@ -115,9 +117,16 @@ func (m Metric) Finish() float64 {
 	n2 := float64(m.n2Distinct())
 	N1 := float64(m.n1Total())
 	N2 := float64(m.n2Total())
+	if n1+n2 == 0 {
+		// nothing was measured
+		return 0
+	}
 	return (N1 + N2) * math.Log2(n1+n2)
 }
 
+// Returns program length, total number of operators and operands
+func (m *Metric) Length() uint { return m.length }
+
 func (m *Metric) n1Distinct() uint { return uint(len(m.operators)) }
 func (m *Metric) n2Distinct() uint { return uint(len(m.operands)) }
 
@@ -168,9 +177,15 @@ func (m *Metric) addToken(nextToken token.Token) {
 
 	if !tokenInArr(nextToken, NOT_OPERATORS[:]) {
 		m.operators[nextToken] += 1
+		m.length++
 	}
 }
 
+func (m *Metric) addOperand(operand string) {
+	m.operands[operand] += 1
+	m.length++
+}
+
 // TODO count commas
 
 func (m *Metric) addAssignStmt(node *ast.AssignStmt) {
@@ -178,7 +193,7 @@ func (m *Metric) addAssignStmt(node *ast.AssignStmt) {
 	m.addToken(node.Tok)
 }
 
-func (m *Metric) addBasicLit(node *ast.BasicLit) { m.operands[node.Value] += 1 }
+func (m *Metric) addBasicLit(node *ast.BasicLit) { m.addOperand(node.Value) }
 
 func (m *Metric) addBinaryExpr(node *ast.BinaryExpr) {
 	// x and y should be visited in walk (expr contains node)
@@ -250,7 +265,7 @@ func (m *Metric) addGenDecl(node *ast.GenDecl) {
 }
 
 func (m *Metric) addGoStmt(node *ast.GoStmt)         { m.addToken(token.GO) }
-func (m *Metric) addIdent(node *ast.Ident)           { m.operands[node.Name] += 1 }
+func (m *Metric) addIdent(node *ast.Ident)           { m.addOperand(node.Name) }
 func (m *Metric) addIfStmt(node *ast.IfStmt)         { m.addToken(token.IF) }
 func (m *Metric) addIncDecStmt(node *ast.IncDecStmt) { m.addToken(node.Tok) }
 
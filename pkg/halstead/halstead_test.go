@@ -145,3 +145,21 @@ func TestMetric(t *testing.T) {
 		}
 	}
 }
+
+func TestLength(t *testing.T) {
+	m := NewMetric()
+	if m.Length() != 0 {
+		t.Errorf("Length() = %v, want 0 for empty metric", m.Length())
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package main; func f() { x := 0; x += 2 }", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		m.ParseNode(n)
+		return true
+	})
+	if got := m.Length(); got != m.n1Total()+m.n2Total() || got != 12 {
+		t.Errorf("Length() = %v, want 12", got)
+	}
+}
@@ -4,58 +4,225 @@ package inline
 import (
 	"go/ast"
 	"go/token"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // Weight of different data complexity
 type Weights struct {
 	// Integer constants complexity
-	Int float64 `xml:"int"`
+	Int float64 `xml:"int" yaml:"int" json:"int" toml:"int"`
 	// Float constants complexity
-	Float float64 `xml:"float"`
+	Float float64 `xml:"float" yaml:"float" json:"float" toml:"float"`
 	// Imaginary numbers constants complexity
-	Imag float64 `xml:"imag"`
+	Imag float64 `xml:"imag" yaml:"imag" json:"imag" toml:"imag"`
 	// Characters constants complexity
-	Char float64 `xml:"char"`
-	// Strings constants complexity
-	String float64 `xml:"string"`
+	Char float64 `xml:"char" yaml:"char" json:"char" toml:"char"`
+	// Interpreted (double-quoted) strings complexity per character
+	String float64 `xml:"string" yaml:"string" json:"string" toml:"string"`
+	// Raw (back-quoted) strings complexity per character
+	RawString float64 `xml:"raw_string" yaml:"raw_string" json:"raw_string" toml:"raw_string"`
+	// Complexity per character of strings with format verbs (like `%v`), added
+	// to string weights
+	FormatString float64 `xml:"format_string" yaml:"format_string" json:"format_string" toml:"format_string"`
+	// Complexity per character of strings looking like regular expressions,
+	// added to string weights
+	RegexString float64 `xml:"regex_string" yaml:"regex_string" json:"regex_string" toml:"regex_string"`
+	// Complexity per character of strings looking like SQL queries, added to
+	// string weights
+	SQLString float64 `xml:"sql_string" yaml:"sql_string" json:"sql_string" toml:"sql_string"`
 	// Composite literals (like structure initialization) constants complexity
-	CompositeLit float64 `xml:"composite"`
+	CompositeLit float64 `xml:"composite" yaml:"composite" json:"composite" toml:"composite"`
+	// Complexity of every element of composite literal
+	Element float64 `xml:"element" yaml:"element" json:"element" toml:"element"`
+	// Additional complexity of every key-value element of composite literal
+	KeyValue float64 `xml:"key_value" yaml:"key_value" json:"key_value" toml:"key_value"`
+	// Complexity of every nesting level of composite literal inside another one
+	Depth float64 `xml:"depth" yaml:"depth" json:"depth" toml:"depth"`
+}
+
+// Raw counts of inline data, fields are named after weights. Strings belong
+// to the first class they look like: SQL query, format string or regular
+// expression. Characters of such strings are also split by quotes, because
+// they are weighted by string weights as well
+type Counts struct {
+	// Number of integer constants
+	Int float64 `json:"int"`
+	// Number of float constants
+	Float float64 `json:"float"`
+	// Number of imaginary constants
+	Imag float64 `json:"imag"`
+	// Number of character constants
+	Char float64 `json:"char"`
+	// Characters of interpreted strings without class
+	String float64 `json:"string"`
+	// Characters of raw strings without class
+	RawString float64 `json:"raw_string"`
+	// Characters of interpreted format strings
+	FormatString float64 `json:"format_string"`
+	// Characters of raw format strings
+	RawFormatString float64 `json:"raw_format_string"`
+	// Characters of interpreted strings looking like regular expressions
+	RegexString float64 `json:"regex_string"`
+	// Characters of raw strings looking like regular expressions
+	RawRegexString float64 `json:"raw_regex_string"`
+	// Characters of interpreted strings looking like SQL queries
+	SQLString float64 `json:"sql_string"`
+	// Characters of raw strings looking like SQL queries
+	RawSQLString float64 `json:"raw_sql_string"`
+	// Number of composite literals
+	CompositeLit float64 `json:"composite"`
+	// Number of elements of composite literals
+	Element float64 `json:"element"`
+	// Number of key-value elements of composite literals
+	KeyValue float64 `json:"key_value"`
+	// Sum of nesting levels of composite literals
+	Depth float64 `json:"depth"`
+}
+
+// Computes weighted result from counts
+func (c Counts) Score(config *Weights) float64 {
+	score := c.Int*config.Int + c.Float*config.Float + c.Imag*config.Imag + c.Char*config.Char +
+		c.String*config.String + c.RawString*config.RawString +
+		c.CompositeLit*config.CompositeLit + c.Element*config.Element + c.KeyValue*config.KeyValue +
+		c.Depth*config.Depth
+	score += classScore(c.FormatString, c.RawFormatString, config.FormatString, config)
+	score += classScore(c.RegexString, c.RawRegexString, config.RegexString, config)
+	score += classScore(c.SQLString, c.RawSQLString, config.SQLString, config)
+	return score
+}
+
+// Weighs characters of a string class by string weights and class weight, so
+// score is linear in every weight
+func classScore(interpreted, raw, weight float64, config *Weights) float64 {
+	return interpreted*config.String + raw*config.RawString + (interpreted+raw)*weight
 }
 
 // Intermidiate state of metric
 type Metric struct {
 	// Config with all metric's weights
 	Config Weights
-	comp   float64
+	counts Counts
+	// nesting levels of composite literals inside other ones
+	depths map[*ast.CompositeLit]uint
 }
 
 // Parses ast node and collects all info about inlined constants in code
-func (m *Metric) ParseNode(n ast.Node) {
+func (m *Metric) ParseNode(n ast.Node) { m.count(n, &m.counts) }
+
+// Parses node like `ParseNode`, but returns counts of node instead of
+// collecting them
+func (m *Metric) Count(n ast.Node) (c Counts) {
+	m.count(n, &c)
+	return
+}
+
+func (m *Metric) count(n ast.Node, c *Counts) {
 	switch v := n.(type) {
 	case *ast.BasicLit:
-		m.comp += getBasicLitComp(v, &m.Config)
+		countBasicLit(v, c)
 	case *ast.CompositeLit:
-		m.comp += m.Config.CompositeLit
+		// outer literals are visited first, so their depth is already known
+		depth := m.depths[v]
+		for _, nested := range nestedLits(v) {
+			if m.depths == nil {
+				m.depths = make(map[*ast.CompositeLit]uint)
+			}
+			m.depths[nested] = depth + 1
+		}
+		countCompositeLit(v, depth, c)
 	}
 }
 
 // Returns final metric's result
-func (m Metric) Finish() float64 { return m.comp }
+func (m Metric) Finish() float64 { return m.counts.Score(&m.Config) }
+
+// Returns raw counts collected so far
+func (m Metric) Counts() Counts { return m.counts }
+
+func countCompositeLit(literal *ast.CompositeLit, depth uint, c *Counts) {
+	c.CompositeLit++
+	c.Element += float64(len(literal.Elts))
+	c.Depth += float64(depth)
+	for _, elt := range literal.Elts {
+		if _, ok := elt.(*ast.KeyValueExpr); ok {
+			c.KeyValue++
+		}
+	}
+}
+
+// Returns composite literals which are elements (keys or values) of given one
+func nestedLits(literal *ast.CompositeLit) []*ast.CompositeLit {
+	var nested []*ast.CompositeLit
+	for _, elt := range literal.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			nested = appendLit(nested, kv.Key)
+			nested = appendLit(nested, kv.Value)
+		} else {
+			nested = appendLit(nested, elt)
+		}
+	}
+	return nested
+}
 
-func getBasicLitComp(literal *ast.BasicLit, config *Weights) float64 {
-	var comp float64
-	len := float64(len(literal.Value))
+func appendLit(lits []*ast.CompositeLit, expr ast.Expr) []*ast.CompositeLit {
+	switch v := expr.(type) {
+	case *ast.CompositeLit:
+		return append(lits, v)
+	case *ast.UnaryExpr:
+		// e.g. `[]*T{&T{}}`
+		return appendLit(lits, v.X)
+	case *ast.ParenExpr:
+		return appendLit(lits, v.X)
+	}
+	return lits
+}
+
+func countBasicLit(literal *ast.BasicLit, c *Counts) {
 	switch literal.Kind {
 	case token.INT:
-		comp = config.Int
+		c.Int++
 	case token.FLOAT:
-		comp = config.Float
+		c.Float++
 	case token.IMAG:
-		comp = config.Imag
+		c.Imag++
 	case token.CHAR:
-		comp = config.Char
+		c.Char++
 	case token.STRING:
-		comp = config.String * len
+		countString(literal.Value, c)
+	}
+}
+
+var (
+	formatVerb = regexp.MustCompile(`%[-+# 0]*(\[\d+\])?(\d+|\*)?(\.(\d+|\*)?)?(\[\d+\])?[vTtbcdoOqxXUeEfFgGsp]`)
+	sqlQuery   = regexp.MustCompile(`(?is)^\s*(select\s.*\sfrom\s|insert\s+into\s|update\s.*\sset\s|` +
+		`delete\s+from\s|(create|alter|drop)\s+(table|index|view)\s|with\s.*\sas\s*\()`)
+	regexSyntax = regexp.MustCompile(`\\[dwsbDWSB]|\(\?|\.[*+]|\[\^|\[[^\]]+-[^\]]+\]|^\^.*\$$`)
+)
+
+// Counts unquoted characters of string literal in its class
+func countString(literal string, c *Counts) {
+	value, err := strconv.Unquote(literal)
+	if err != nil {
+		value = literal
+	}
+	runes := float64(utf8.RuneCountInString(value))
+
+	raw := strings.HasPrefix(literal, "`")
+	interpreted, rawCount := &c.String, &c.RawString
+	switch {
+	case sqlQuery.MatchString(value):
+		interpreted, rawCount = &c.SQLString, &c.RawSQLString
+	case formatVerb.MatchString(strings.ReplaceAll(value, "%%", "")):
+		interpreted, rawCount = &c.FormatString, &c.RawFormatString
+	case regexSyntax.MatchString(value):
+		interpreted, rawCount = &c.RegexString, &c.RawRegexString
+	}
+	if raw {
+		*rawCount += runes
+	} else {
+		*interpreted += runes
 	}
-	return comp
 }
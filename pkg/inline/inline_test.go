@@ -31,7 +31,7 @@ func TestInlineData(t *testing.T) {
 			
 	func main(){
 		fmt.Printf("Result: %v", foo(1))
-	}`, 23},
+	}`, 19},
 		{`package main
 
 	import (
@@ -48,7 +48,7 @@ func TestInlineData(t *testing.T) {
 	
 	func main() {
 		fmt.Printf("Result: %v\n", foo(1))
-	}`, 37},
+	}`, 29},
 		{`package main
 
 	import (
@@ -62,7 +62,7 @@ func TestInlineData(t *testing.T) {
 	
 	func main() {
 		foo()
-	}`, 37},
+	}`, 28},
 	}
 	cfg := Weights{Int: 2, Float: 2, Imag: 2, Char: 1, String: 1, CompositeLit: 5}
 	tests := make([]struct {
@@ -95,3 +95,70 @@ func TestInlineData(t *testing.T) {
 		})
 	}
 }
+
+func TestCompositeLit(t *testing.T) {
+	cfg := Weights{CompositeLit: 1, Element: 10, KeyValue: 100, Depth: 1000}
+	tests := []struct {
+		name string
+		src  string
+		want float64
+	}{
+		{"Empty", `var a = T{}`, 1},
+		{"Elements", `var a = []int{x, y, z}`, 31},
+		{"KeyValues", `var a = T{A: x, B: y}`, 221},
+		{"Nested", `var a = []T{{x}, {y}}`, 21 + 2*1011},
+		{"Pointers", `var a = []*T{&T{A: x}}`, 11 + 1111},
+		{"DeepMap", `var a = map[K][]V{{x}: {{y}}}`, 111 + 2*1011 + 2011},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+tt.src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := Metric{Config: cfg}
+			ast.Inspect(file, func(n ast.Node) bool {
+				m.ParseNode(n)
+				return true
+			})
+			if got := m.Finish(); got != tt.want {
+				t.Errorf("Finish() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStringComp(t *testing.T) {
+	cfg := Weights{String: 1, RawString: 2, FormatString: 3, RegexString: 4, SQLString: 5}
+	tests := []struct {
+		name    string
+		literal string
+		want    float64
+	}{
+		{"Empty", `""`, 0},
+		{"Escapes", `"a\tb\n"`, 4},
+		{"Unicode", `"привет"`, 6},
+		{"Raw", "`a\\tb`", 8},
+		{"Format", `"%-5d items"`, 40},
+		{"Percent", `"100%% done"`, 10},
+		{"Regex", "`^[a-z]+\\d*$`", 66},
+		{"SQL", `"SELECT id FROM users WHERE name = %s"`, 216},
+		{"Plain", `"not a regex: dot. or plus+"`, 26},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Counts
+			countString(tt.literal, &c)
+			if got := c.Score(&cfg); got != tt.want {
+				t.Errorf("score of %v = %v, want %v", tt.literal, got, tt.want)
+			}
+		})
+	}
+
+	// disabled content classes are weighted only as strings
+	var c Counts
+	countString(`"%v"`, &c)
+	if got := c.Score(&Weights{String: 1}); got != 2 {
+		t.Errorf("score of %%v = %v, want 2", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Package loc counts physical, source, comment and blank lines of go code.
+package loc
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// Line counts of some piece of code
+type Lines struct {
+	// Total number of lines
+	Physical uint `json:"physical"`
+	// Lines containing code (possibly with trailing comments)
+	Source uint `json:"source"`
+	// Lines containing only comments
+	Comment uint `json:"comment"`
+	// Lines without code and comments
+	Blank uint `json:"blank"`
+}
+
+// Sums line counts
+func (l Lines) Add(other Lines) Lines {
+	return Lines{
+		Physical: l.Physical + other.Physical,
+		Source:   l.Source + other.Source,
+		Comment:  l.Comment + other.Comment,
+		Blank:    l.Blank + other.Blank,
+	}
+}
+
+// Returns score spent per source line, zero if there is no code
+func (l Lines) PerSource(score float64) float64 {
+	if l.Source == 0 {
+		return 0
+	}
+	return score / float64(l.Source)
+}
+
+// Classification of lines of a single parsed file
+type Counter struct {
+	file    *token.File
+	code    map[int]bool
+	comment map[int]bool
+}
+
+// Constructor of counter, file should be parsed with comments to count them
+func NewCounter(fset *token.FileSet, file *ast.File) *Counter {
+	c := &Counter{
+		file:    fset.File(file.Pos()),
+		code:    make(map[int]bool),
+		comment: make(map[int]bool),
+	}
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch v := n.(type) {
+		case nil, *ast.CommentGroup, *ast.Comment:
+			return false
+		case *ast.BasicLit:
+			// raw strings may span several lines
+			c.mark(c.code, v.Pos(), v.End())
+		default:
+			// inner lines of multiline nodes are marked by their children
+			if v.Pos().IsValid() && v.End().IsValid() {
+				c.code[c.file.Line(v.Pos())] = true
+				c.code[c.lastLine(v.End())] = true
+			}
+		}
+		return true
+	})
+	for _, group := range file.Comments {
+		for _, comment := range group.List {
+			c.mark(c.comment, comment.Pos(), comment.End())
+		}
+	}
+	return c
+}
+
+// Counts lines of the whole file
+func (c *Counter) File() Lines {
+	return c.count(1, c.file.LineCount())
+}
+
+// Counts lines occupied by code from `pos` to `end` (exclusive), like ones
+// returned by `Pos` and `End` of ast nodes
+func (c *Counter) Count(pos, end token.Pos) Lines {
+	return c.count(c.file.Line(pos), c.lastLine(end))
+}
+
+func (c *Counter) count(first, last int) (lines Lines) {
+	for line := first; line <= last; line++ {
+		lines.Physical++
+		switch {
+		case c.code[line]:
+			lines.Source++
+		case c.comment[line]:
+			lines.Comment++
+		default:
+			lines.Blank++
+		}
+	}
+	return
+}
+
+func (c *Counter) mark(lines map[int]bool, pos, end token.Pos) {
+	if !pos.IsValid() || !end.IsValid() {
+		return
+	}
+	for line := c.file.Line(pos); line <= c.lastLine(end); line++ {
+		lines[line] = true
+	}
+}
+
+// End positions point right after the node, so step back to stay on its line
+func (c *Counter) lastLine(end token.Pos) int { return c.file.Line(end - 1) }
@@ -0,0 +1,61 @@
+package loc
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestCounter(t *testing.T) {
+	src := `package main
+
+// Doc comment
+// on two lines
+func main() {
+	a := 1 // trailing comment
+
+	/* block
+	   comment */
+	b := ` + "`raw\n\nstring`" + `
+	_, _ = a, b
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewCounter(fset, file)
+
+	if got, want := c.File(), (Lines{14, 8, 4, 2}); got != want {
+		t.Errorf("File() = %+v, want %+v", got, want)
+	}
+
+	fd := file.Decls[0].(*ast.FuncDecl)
+	if got, want := c.Count(fd.Doc.Pos(), fd.End()), (Lines{12, 7, 4, 1}); got != want {
+		t.Errorf("Count(func) = %+v, want %+v", got, want)
+	}
+	if got, want := c.Count(fd.Pos(), fd.End()), (Lines{10, 7, 2, 1}); got != want {
+		t.Errorf("Count(func without doc) = %+v, want %+v", got, want)
+	}
+}
+
+func TestPerSource(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines Lines
+		score float64
+		want  float64
+	}{
+		{"NoCode", Lines{Physical: 3, Blank: 3}, 10, 0},
+		{"Code", Lines{Physical: 5, Source: 4, Blank: 1}, 10, 2.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.lines.PerSource(tt.score); got != tt.want {
+				t.Errorf("PerSource() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
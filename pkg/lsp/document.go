@@ -0,0 +1,279 @@
+package lsp
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/bragov4ik/go-kys/pkg/pragma"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Number of constructs listed in hover
+const topConstructs = 3
+
+// Measured contents of open document
+type document struct {
+	path   string
+	text   string
+	fset   *token.FileSet
+	tokens *token.File
+	cfg    wmfp.Config
+	// directives of file, they apply to constructs measured separately as well
+	directives *pragma.Directives
+	funcs      []function
+}
+
+// Measured function of document
+type function struct {
+	decl      *ast.FuncDecl
+	result    report.Func
+	breakdown wmfp.Breakdown
+	// statements of function body sorted by minutes, largest first
+	constructs []construct
+}
+
+// Statement measured on its own
+type construct struct {
+	node    ast.Stmt
+	minutes float64
+}
+
+// Parses and measures text of document, unsaved text is measured instead of
+// file at `path`
+func analyze(path, text string, cfg wmfp.Config) (*document, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, text, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	result, err := report.Measure(fset, path, file, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	directives, err := pragma.Parse(fset, file)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{
+		path:       path,
+		text:       text,
+		fset:       fset,
+		tokens:     fset.File(file.Pos()),
+		cfg:        cfg,
+		directives: directives,
+	}
+	// functions of report are in order of declarations
+	i := 0
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		fn := function{decl: fd, result: result.Funcs[i]}
+		fn.breakdown = fn.result.Counts.Breakdown(&doc.cfg)
+		if fd.Body != nil {
+			for _, stmt := range fd.Body.List {
+				fn.constructs = append(fn.constructs, construct{stmt, doc.measure(stmt)})
+			}
+		}
+		sort.SliceStable(fn.constructs, func(i, j int) bool { return fn.constructs[i].minutes > fn.constructs[j].minutes })
+		doc.funcs = append(doc.funcs, fn)
+		i++
+	}
+	return doc, nil
+}
+
+// Measures node on its own
+func (d *document) measure(node ast.Node) float64 {
+	m := wmfp.NewMeasurerWMFP(&d.cfg)
+	m.Directives = d.directives
+	m.Parse(node)
+	return m.Finish()
+}
+
+// Returns function containing position, nil if there is none
+func (d *document) funcAt(pos token.Pos) *function {
+	for i := range d.funcs {
+		fn := &d.funcs[i]
+		start := fn.decl.Pos()
+		if fn.decl.Doc != nil {
+			start = fn.decl.Doc.Pos()
+		}
+		if start <= pos && pos < fn.decl.End() {
+			return fn
+		}
+	}
+	return nil
+}
+
+// Returns innermost statement of function containing position, nil if there is none
+func stmtAt(fn *function, pos token.Pos) ast.Stmt {
+	var found ast.Stmt
+	if fn.decl.Body == nil {
+		return nil
+	}
+	ast.Inspect(fn.decl.Body, func(n ast.Node) bool {
+		if n == nil || pos < n.Pos() || n.End() <= pos {
+			return false
+		}
+		if stmt, ok := n.(ast.Stmt); ok && n != fn.decl.Body {
+			if _, block := n.(*ast.BlockStmt); !block {
+				found = stmt
+			}
+		}
+		return true
+	})
+	return found
+}
+
+// Returns code lens above every function
+func (d *document) codeLenses() []CodeLens {
+	lenses := []CodeLens{}
+	for _, fn := range d.funcs {
+		line := d.position(fn.decl.Pos()).Line
+		title := fmt.Sprintf("%.2f min", fn.result.Score)
+		for _, part := range topParts(fn.breakdown, 3) {
+			title += fmt.Sprintf(" · %s %.2f", part.Name, part.Minutes)
+		}
+		lenses = append(lenses, CodeLens{
+			Range:   Range{Position{line, 0}, Position{line, 0}},
+			Command: &Command{Title: title, Command: LensCommand},
+		})
+	}
+	return lenses
+}
+
+// Returns hover with breakdown of function at position, nil outside functions
+func (d *document) hover(p Position) *Hover {
+	pos, ok := d.pos(p)
+	if !ok {
+		return nil
+	}
+	fn := d.funcAt(pos)
+	if fn == nil {
+		return nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s** — %.2f WMFP minutes", fn.result.Name, fn.result.Score)
+	if fn.result.Suppressed != 0 {
+		fmt.Fprintf(&b, " (%.2f suppressed by directives)", fn.result.Suppressed)
+	}
+	b.WriteString("\n\n| metric | minutes |\n|---|---:|\n")
+	for _, part := range topParts(fn.breakdown, len(fn.breakdown.Parts())) {
+		fmt.Fprintf(&b, "| %s | %.2f |\n", part.Name, part.Minutes)
+	}
+
+	if len(fn.constructs) > 0 {
+		b.WriteString("\nLargest statements:\n")
+		for i, c := range fn.constructs {
+			if i == topConstructs || c.minutes == 0 {
+				break
+			}
+			fmt.Fprintf(&b, "- line %d, %s: %.2f min\n", d.fset.Position(c.node.Pos()).Line, describe(c.node), c.minutes)
+		}
+	}
+	if stmt := stmtAt(fn, pos); stmt != nil {
+		fmt.Fprintf(&b, "\nStatement under cursor (%s): %.2f min\n", describe(stmt), d.measure(stmt))
+	}
+
+	start, end := d.position(fn.decl.Pos()), d.position(fn.decl.End())
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: b.String()},
+		Range:    &Range{start, end},
+	}
+}
+
+// Returns non zero metrics sorted by minutes, at most `n`
+func topParts(b wmfp.Breakdown, n int) []wmfp.Part {
+	var parts []wmfp.Part
+	for _, part := range b.Parts() {
+		if part.Minutes != 0 {
+			parts = append(parts, part)
+		}
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Minutes > parts[j].Minutes })
+	if len(parts) > n {
+		parts = parts[:n]
+	}
+	return parts
+}
+
+// Returns short description of statement
+func describe(stmt ast.Stmt) string {
+	switch s := stmt.(type) {
+	case *ast.IfStmt:
+		return "if"
+	case *ast.ForStmt:
+		return "for loop"
+	case *ast.RangeStmt:
+		return "range loop"
+	case *ast.SwitchStmt:
+		return "switch"
+	case *ast.TypeSwitchStmt:
+		return "type switch"
+	case *ast.SelectStmt:
+		return "select"
+	case *ast.CaseClause:
+		return "case"
+	case *ast.CommClause:
+		return "select case"
+	case *ast.BranchStmt:
+		return s.Tok.String()
+	case *ast.ReturnStmt:
+		return "return"
+	case *ast.AssignStmt:
+		return "assignment"
+	case *ast.DeclStmt:
+		return "declaration"
+	case *ast.GoStmt:
+		return "go statement"
+	case *ast.DeferStmt:
+		return "defer"
+	case *ast.ExprStmt:
+		if _, ok := s.X.(*ast.CallExpr); ok {
+			return "call"
+		}
+	case *ast.IncDecStmt:
+		return s.Tok.String()
+	}
+	return "statement"
+}
+
+// Converts position of token to LSP position
+func (d *document) position(pos token.Pos) Position {
+	p := d.fset.Position(pos)
+	start := d.tokens.Offset(d.tokens.LineStart(p.Line))
+	return Position{Line: p.Line - 1, Character: utf16Len(d.text[start:p.Offset])}
+}
+
+// Converts LSP position to position of token, false if it is outside of document
+func (d *document) pos(p Position) (token.Pos, bool) {
+	if p.Line < 0 || p.Line >= d.tokens.LineCount() {
+		return token.NoPos, false
+	}
+	offset := d.tokens.Offset(d.tokens.LineStart(p.Line + 1))
+	for units := 0; units < p.Character && offset < len(d.text) && d.text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(d.text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return d.tokens.Pos(offset), true
+}
+
+// Returns length of string in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
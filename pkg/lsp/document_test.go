@@ -0,0 +1,82 @@
+package lsp
+
+import (
+	"strings"
+	"testing"
+
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+func TestDocument(t *testing.T) {
+	src := `package a
+
+// Greets 🌍
+func greet(names []string) (s string) {
+	for _, n := range names {
+		s += "👋 " + n
+	}
+	//gokys:ignore
+	if len(names) > 100 {
+		panic("too many")
+	}
+	return
+}
+
+var x = 1
+`
+	cfg := wmfp.Config{CycloComp: cyclo.Weights{If: 2, Rng: 1}, Halstead: 0.1}
+	doc, err := analyze("/tmp/a.go", src, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		pos  Position
+	}{
+		{"LineStart", Position{4, 0}},
+		{"AfterEmoji", Position{5, 10}},
+		{"Comment", Position{2, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pos, ok := doc.pos(tt.pos)
+			if !ok {
+				t.Fatalf("pos(%v) is outside of document", tt.pos)
+			}
+			if got := doc.position(pos); got != tt.pos {
+				t.Errorf("position(pos(%v)) = %v", tt.pos, got)
+			}
+		})
+	}
+	// "👋" is two UTF-16 code units and four bytes
+	pos, _ := doc.pos(Position{5, 10})
+	if line := strings.Split(src, "\n")[5]; doc.fset.Position(pos).Offset-strings.Index(src, line) != 12 {
+		t.Errorf("pos after emoji has byte column %v, want 12", doc.fset.Position(pos).Column-1)
+	}
+	if _, ok := doc.pos(Position{100, 0}); ok {
+		t.Error("pos() should fail outside of document")
+	}
+
+	if len(doc.funcs) != 1 {
+		t.Fatalf("got %d functions, want 1", len(doc.funcs))
+	}
+	fn := doc.funcs[0]
+	if fn.constructs[0].minutes < fn.constructs[1].minutes || describe(fn.constructs[0].node) != "range loop" {
+		t.Errorf("largest construct is %v, want range loop", describe(fn.constructs[0].node))
+	}
+	for _, c := range fn.constructs {
+		if describe(c.node) == "if" && c.minutes != 0 {
+			t.Errorf("ignored if statement has %v minutes", c.minutes)
+		}
+	}
+
+	if hover := doc.hover(Position{14, 4}); hover != nil {
+		t.Errorf("hover outside of functions = %+v, want nil", hover)
+	}
+	hover := doc.hover(Position{2, 3})
+	if hover == nil || !strings.Contains(hover.Contents.Value, "suppressed") || hover.Range.Start.Line != 3 {
+		t.Errorf("hover on doc comment = %+v, want function with suppressed minutes", hover)
+	}
+}
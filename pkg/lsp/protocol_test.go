@@ -0,0 +1,63 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadMessage(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeMessage(&buf, &message{ID: json.RawMessage("1"), Method: "shutdown"}); err != nil {
+		t.Fatal(err)
+	}
+	buf.WriteString("Content-Length: 8\r\nContent-Type: application/vscode-jsonrpc; charset=utf-8\r\n\r\nnot json")
+	buf.WriteString("Content-Length: x\r\n\r\n")
+
+	r := bufio.NewReader(&buf)
+	m, err := readMessage(r)
+	if err != nil || m.Method != "shutdown" || string(m.ID) != "1" || m.JSONRPC != "2.0" {
+		t.Errorf("readMessage() = %+v, %v, want shutdown request", m, err)
+	}
+	var rpcErr *rpcError
+	if _, err := readMessage(r); !errors.As(err, &rpcErr) || rpcErr.Code != codeParseError {
+		t.Errorf("readMessage() error = %v, want parse error", err)
+	}
+	if _, err := readMessage(r); err == nil || !strings.Contains(err.Error(), "Content-Length") {
+		t.Errorf("readMessage() error = %v, want invalid header", err)
+	}
+
+	for _, length := range []string{"-1", "1099511627776"} {
+		r := bufio.NewReader(strings.NewReader("Content-Length: " + length + "\r\n\r\n{}"))
+		if _, err := readMessage(r); err == nil || !strings.Contains(err.Error(), "Content-Length") {
+			t.Errorf("readMessage() of length %s error = %v, want invalid header", length, err)
+		}
+	}
+}
+
+func TestURIToPath(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want string
+		err  bool
+	}{
+		{"file:///home/user/a.go", "/home/user/a.go", false},
+		{"file:///home/user/my%20dir/a.go", "/home/user/my dir/a.go", false},
+		{"file://localhost/home/user/a.go", "/home/user/a.go", false},
+		{"file:///C:/Users/me/a.go", filepath.FromSlash("C:/Users/me/a.go"), false},
+		{"file:///c%3A/Users/me/a.go", filepath.FromSlash("c:/Users/me/a.go"), false},
+		{"file://server/share/a.go", filepath.FromSlash("//server/share/a.go"), false},
+		{"file:///CD/a.go", "/CD/a.go", false},
+		{"untitled:Untitled-1", "", true},
+	}
+	for _, tt := range tests {
+		got, err := uriToPath(tt.uri)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("uriToPath(%v) = %v, %v, want %v", tt.uri, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package lsp implements a minimal language server which shows WMFP minutes of
+// functions as code lenses and their breakdown on hover.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Language server working over a single stream, e.g. stdio
+type Server struct {
+	// Returns config for file at path
+	Config func(path string) wmfp.Config
+	// Receives errors which can not be returned to client, e.g. when
+	// document fails to parse. Ignored if nil
+	Log func(error)
+
+	docs        map[string]*document
+	initialized bool
+	shutdown    bool
+}
+
+// Creates server using given configs
+func NewServer(config func(path string) wmfp.Config) *Server {
+	return &Server{Config: config, docs: make(map[string]*document)}
+}
+
+// Serves requests until `exit` notification, returns error if stream ended or
+// client exited without `shutdown` request
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	for {
+		m, err := readMessage(r)
+		var rpcErr *rpcError
+		if errors.As(err, &rpcErr) {
+			if err := writeMessage(out, &message{ID: json.RawMessage("null"), Error: rpcErr}); err != nil {
+				return err
+			}
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if m.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(m)
+		if m.ID == nil {
+			// notifications have no responses
+			if err != nil {
+				s.log(err)
+			}
+			continue
+		}
+
+		response := &message{ID: m.ID}
+		if err != nil {
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &rpcError{Code: codeInvalidParams, Message: err.Error()}
+			}
+			response.Error = rpcErr
+		} else if response.Result, err = json.Marshal(result); err != nil {
+			return err
+		}
+		if err := writeMessage(out, response); err != nil {
+			return err
+		}
+	}
+}
+
+// Handles request or notification, result is ignored for notifications
+func (s *Server) handle(m *message) (interface{}, error) {
+	if !s.initialized && m.Method != "initialize" {
+		return nil, &rpcError{Code: codeNotInitialized, Message: "server is not initialized"}
+	}
+	if s.shutdown {
+		return nil, &rpcError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch m.Method {
+	case "initialize":
+		s.initialized = true
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				// full text is sent on every change
+				"textDocumentSync": 1,
+				"codeLensProvider": map[string]interface{}{"resolveProvider": false},
+				// clients run command of lens when it is clicked
+				"executeCommandProvider": map[string]interface{}{"commands": []string{LensCommand}},
+				"hoverProvider":          true,
+				"positionEncoding":       "utf-16",
+			},
+			"serverInfo": map[string]string{"name": "gokys"},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		return nil, s.update(params.TextDocument.URI, params.TextDocument.Text)
+	case "textDocument/didChange":
+		var params didChangeParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		if len(params.ContentChanges) == 0 {
+			return nil, nil
+		}
+		change := params.ContentChanges[len(params.ContentChanges)-1]
+		if change.Range != nil {
+			return nil, errors.New("incremental changes are not supported")
+		}
+		return nil, s.update(params.TextDocument.URI, change.Text)
+	case "textDocument/didClose":
+		var params documentParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/codeLens":
+		var params documentParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return []CodeLens{}, nil
+		}
+		return doc.codeLenses(), nil
+	case "workspace/executeCommand":
+		var params struct {
+			Command string `json:"command"`
+		}
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		if params.Command != LensCommand {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown command %q", params.Command)}
+		}
+		return nil, nil
+	case "textDocument/hover":
+		var params positionParams
+		if err := json.Unmarshal(m.Params, &params); err != nil {
+			return nil, err
+		}
+		doc, ok := s.docs[params.TextDocument.URI]
+		if !ok {
+			return nil, nil
+		}
+		if hover := doc.hover(params.Position); hover != nil {
+			return hover, nil
+		}
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q is not supported", m.Method)}
+}
+
+// Measures new text of document. Text which fails to parse (e.g. while typing)
+// keeps results of previous text
+func (s *Server) update(uri, text string) error {
+	path, err := uriToPath(uri)
+	if err != nil {
+		return err
+	}
+	doc, err := analyze(path, text, s.Config(path))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	s.docs[uri] = doc
+	return nil
+}
+
+func (s *Server) log(err error) {
+	if s.Log != nil {
+		s.Log(err)
+	}
+}
@@ -0,0 +1,197 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Scripted client talking to server over pipes
+type client struct {
+	t      *testing.T
+	in     io.WriteCloser
+	out    *bufio.Reader
+	nextID int
+}
+
+func (c *client) notify(method string, params interface{}) {
+	c.t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := writeMessage(c.in, &message{Method: method, Params: data}); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// Sends request and decodes result of response into `result`, returns error of response
+func (c *client) call(method string, params, result interface{}) *rpcError {
+	c.t.Helper()
+	c.nextID++
+	data, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	id := json.RawMessage(strconv.Itoa(c.nextID))
+	if err := writeMessage(c.in, &message{ID: id, Method: method, Params: data}); err != nil {
+		c.t.Fatal(err)
+	}
+	response, err := readMessage(c.out)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if string(response.ID) != string(id) {
+		c.t.Fatalf("response id = %s, want %s", response.ID, id)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result != nil {
+		if err := json.Unmarshal(response.Result, result); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return nil
+}
+
+const uri = "file:///tmp/gokys-lsp/a.go"
+
+const source = `package a
+
+// Returns sign of x
+func sign(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+`
+
+func TestServer(t *testing.T) {
+	cfg := wmfp.Config{CycloComp: cyclo.Weights{If: 2, For: 3}, Halstead: 0.1}
+	server := NewServer(func(string) wmfp.Config { return cfg })
+	var logged []error
+	server.Log = func(err error) { logged = append(logged, err) }
+
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(inR, outW)
+		outW.Close()
+	}()
+	c := &client{t: t, in: inW, out: bufio.NewReader(outR)}
+
+	if err := c.call("textDocument/codeLens", map[string]interface{}{}, nil); err == nil || err.Code != codeNotInitialized {
+		t.Errorf("request before initialize returned %v, want not initialized error", err)
+	}
+
+	var init struct {
+		Capabilities struct {
+			CodeLensProvider       interface{} `json:"codeLensProvider"`
+			HoverProvider          bool        `json:"hoverProvider"`
+			ExecuteCommandProvider struct {
+				Commands []string `json:"commands"`
+			} `json:"executeCommandProvider"`
+		} `json:"capabilities"`
+	}
+	if err := c.call("initialize", map[string]interface{}{"processId": nil}, &init); err != nil {
+		t.Fatal(err)
+	}
+	if init.Capabilities.CodeLensProvider == nil || !init.Capabilities.HoverProvider {
+		t.Errorf("initialize result = %+v, want code lens and hover providers", init)
+	}
+	if commands := init.Capabilities.ExecuteCommandProvider.Commands; len(commands) != 1 || commands[0] != LensCommand {
+		t.Errorf("commands = %v, want command of code lenses", commands)
+	}
+	c.notify("initialized", struct{}{})
+
+	doc := map[string]interface{}{"textDocument": map[string]interface{}{"uri": uri}}
+	c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "go", "version": 1, "text": source},
+	})
+	var lenses []CodeLens
+	if err := c.call("textDocument/codeLens", doc, &lenses); err != nil {
+		t.Fatal(err)
+	}
+	if len(lenses) != 1 || lenses[0].Range.Start.Line != 3 || !strings.Contains(lenses[0].Command.Title, "cyclomatic 3.00") {
+		t.Fatalf("code lenses = %+v, want lens of sign on line 3", lenses)
+	}
+	if lenses[0].Command.Command != LensCommand {
+		t.Errorf("code lens command = %q, want %q", lenses[0].Command.Command, LensCommand)
+	}
+	if err := c.call("workspace/executeCommand", map[string]interface{}{"command": LensCommand}, nil); err != nil {
+		t.Errorf("executeCommand(%v) = %v, want no error", LensCommand, err)
+	}
+	if err := c.call("workspace/executeCommand", map[string]interface{}{"command": "x"}, nil); err == nil || err.Code != codeInvalidParams {
+		t.Errorf("executeCommand(x) = %v, want invalid params error", err)
+	}
+	before := lenses[0].Command.Title
+
+	changed := strings.Replace(source, "if x > 0 {", "for x > 0 {", 1)
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 2},
+		"contentChanges": []map[string]interface{}{{"text": changed}},
+	})
+	if err := c.call("textDocument/codeLens", doc, &lenses); err != nil {
+		t.Fatal(err)
+	}
+	if len(lenses) != 1 || lenses[0].Command.Title == before || !strings.Contains(lenses[0].Command.Title, "cyclomatic 4.00") {
+		t.Errorf("code lenses after change = %+v, want for loop counted", lenses)
+	}
+
+	// unfinished code keeps previous results
+	c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": 3},
+		"contentChanges": []map[string]interface{}{{"text": "package a\n\nfunc sign(x int"}},
+	})
+	if err := c.call("textDocument/codeLens", doc, &lenses); err != nil {
+		t.Fatal(err)
+	}
+	if len(lenses) != 1 || len(logged) != 1 {
+		t.Errorf("code lenses after syntax error = %+v, logged %v, want previous lenses and logged error", lenses, logged)
+	}
+
+	var hover Hover
+	position := map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"position":     map[string]int{"line": 5, "character": 3},
+	}
+	if err := c.call("textDocument/hover", position, &hover); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"**sign**", "| cyclomatic | 4.00 |", "line 5, for loop", "Statement under cursor (return)"} {
+		if !strings.Contains(hover.Contents.Value, want) {
+			t.Errorf("hover = %q, should contain %q", hover.Contents.Value, want)
+		}
+	}
+
+	if err := c.call("workspace/symbol", struct{}{}, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("unknown method returned %v, want method not found error", err)
+	}
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.notify("exit", nil)
+	if err := <-done; err != nil {
+		t.Errorf("Serve() = %v, want nil after shutdown and exit", err)
+	}
+}
+
+func TestServerExitWithoutShutdown(t *testing.T) {
+	var in strings.Builder
+	if err := writeMessage(&in, &message{Method: "exit"}); err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(func(string) wmfp.Config { return wmfp.Config{} })
+	if err := server.Serve(strings.NewReader(in.String()), io.Discard); err == nil {
+		t.Error("Serve() should fail when client exits without shutdown")
+	}
+}
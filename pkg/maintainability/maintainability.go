@@ -0,0 +1,78 @@
+// Package maintainability computes [Maintainability Index](https://learn.microsoft.com/en-us/visualstudio/code-quality/code-metrics-maintainability-index-range-and-meaning)
+// from halstead volume, cyclomatic complexity and source lines count.
+package maintainability
+
+import (
+	"go/ast"
+	"math"
+
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	halstead "github.com/bragov4ik/go-kys/pkg/halstead"
+)
+
+// Weights giving classic cyclomatic complexity (one per branch)
+var unitWeights = cyclo.Weights{If: 1, For: 1, Rng: 1, Case: 1, And: 1, Or: 1}
+
+// Maintainability index together with values it was computed from
+type Index struct {
+	// Halstead volume
+	Volume float64 `json:"volume"`
+	// Cyclomatic complexity without weights
+	Complexity float64 `json:"complexity"`
+	// Number of source lines
+	SLOC uint `json:"sloc"`
+	// Classic index, 171 at most, may be negative for huge code
+	Value float64 `json:"value"`
+	// Visual Studio variant normalized to range 0..100
+	Normalized float64 `json:"normalized"`
+}
+
+// Computes index from its components
+func Compute(volume, complexity float64, sloc uint) Index {
+	// logarithms of values less than 1 are clamped to keep index finite
+	value := 171 -
+		5.2*math.Log(math.Max(volume, 1)) -
+		0.23*complexity -
+		16.2*math.Log(math.Max(float64(sloc), 1))
+	return Index{
+		Volume:     volume,
+		Complexity: complexity,
+		SLOC:       sloc,
+		Value:      value,
+		Normalized: math.Max(0, value*100/171),
+	}
+}
+
+// Combines indices of code parts, e.g. all files of package. Volumes,
+// complexities and lines are summed, but index values are averaged weighted by
+// SLOC, because logarithms of sums would make big packages look worse than
+// any of their files
+func (i Index) Add(other Index) Index {
+	sloc := i.SLOC + other.SLOC
+	if sloc == 0 {
+		return Compute(i.Volume+other.Volume, i.Complexity+other.Complexity, 0)
+	}
+	average := func(a, b float64) float64 {
+		return (a*float64(i.SLOC) + b*float64(other.SLOC)) / float64(sloc)
+	}
+	return Index{
+		Volume:     i.Volume + other.Volume,
+		Complexity: i.Complexity + other.Complexity,
+		SLOC:       sloc,
+		Value:      average(i.Value, other.Value),
+		Normalized: average(i.Normalized, other.Normalized),
+	}
+}
+
+// Measures halstead volume and cyclomatic complexity of ast subtree with given
+// number of source lines and computes index for it
+func Measure(node ast.Node, sloc uint) Index {
+	halst := halstead.NewMetric()
+	comp := cyclo.Metric{Config: unitWeights}
+	ast.Inspect(node, func(n ast.Node) bool {
+		halst.ParseNode(n)
+		comp.ParseNode(n)
+		return true
+	})
+	return Compute(halst.Finish(), comp.Finish(), sloc)
+}
@@ -0,0 +1,74 @@
+package maintainability
+
+import (
+	"go/parser"
+	"go/token"
+	"math"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	tests := []struct {
+		name       string
+		volume     float64
+		complexity float64
+		sloc       uint
+		want       float64
+	}{
+		{"Empty", 0, 0, 0, 171},
+		{"Small", math.E, 1, 1, 171 - 5.2 - 0.23},
+		{"Big", 1e6, 100, 10000, 171 - 5.2*math.Log(1e6) - 23 - 16.2*math.Log(10000)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Compute(tt.volume, tt.complexity, tt.sloc)
+			if math.Abs(got.Value-tt.want) > 1e-9 {
+				t.Errorf("Compute().Value = %v, want %v", got.Value, tt.want)
+			}
+			if want := math.Max(0, tt.want*100/171); math.Abs(got.Normalized-want) > 1e-9 {
+				t.Errorf("Compute().Normalized = %v, want %v", got.Normalized, want)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	a, b := Compute(10, 2, 5), Compute(2000, 30, 70)
+	got := Index{}.Add(a).Add(b)
+	if got.Volume != 2010 || got.Complexity != 32 || got.SLOC != 75 {
+		t.Errorf("Add() = %+v, want summed components", got)
+	}
+	if want := (a.Value*5 + b.Value*70) / 75; math.Abs(got.Value-want) > 1e-9 {
+		t.Errorf("Add().Value = %v, want %v", got.Value, want)
+	}
+	if got.Value <= b.Value || got.Value >= a.Value || got.Normalized <= b.Normalized || got.Normalized >= a.Normalized {
+		t.Errorf("Add() = %+v, want index between %+v and %+v", got, a, b)
+	}
+	if got := (Index{}).Add(a); got != a {
+		t.Errorf("Add() to empty index = %+v, want %+v", got, a)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	src := `package main
+	func f(a, b bool) {
+		if a && b {
+			for {}
+		}
+	}`
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Measure(file, 5)
+	if got.Complexity != 4 {
+		t.Errorf("Complexity = %v, want 4", got.Complexity)
+	}
+	if got.Volume <= 0 {
+		t.Errorf("Volume = %v, want positive", got.Volume)
+	}
+	if want := Compute(got.Volume, 4, 5); got != want {
+		t.Errorf("Measure() = %+v, want %+v", got, want)
+	}
+}
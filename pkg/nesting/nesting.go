@@ -0,0 +1,194 @@
+// Package with metric which penalizes deeply nested code.
+package nesting
+
+import "go/ast"
+
+// Config for metric
+type Weights struct {
+	// Nesting depth which is not penalized
+	Threshold uint `xml:"threshold" yaml:"threshold" json:"threshold" toml:"threshold"`
+	// Weight of every nesting level beyond threshold
+	Level float64 `xml:"level" yaml:"level" json:"level" toml:"level"`
+}
+
+// Raw counts of metric
+type Counts struct {
+	// Number of nesting constructs by depth of their bodies, threshold is
+	// applied only by `Score`
+	Depth []float64 `json:"depth"`
+}
+
+// Computes weighted result from counts
+func (c Counts) Score(config *Weights) float64 {
+	var levels float64
+	for depth, n := range c.Depth {
+		levels += n * excessLevels(uint(depth), config.Threshold)
+	}
+	return levels * config.Level
+}
+
+func (c *Counts) add(depth uint) {
+	for uint(len(c.Depth)) <= depth {
+		c.Depth = append(c.Depth, 0)
+	}
+	c.Depth[depth]++
+}
+
+// Intermidiate state of metric
+type Metric struct {
+	// Config with weights
+	Config Weights
+	counts Counts
+	// depths of constructs in functions, functions are visited before their
+	// bodies, so constructs are penalized when they are visited themselves
+	depths map[ast.Node]uint
+}
+
+// Parses ast node and penalizes nesting constructs in functions
+func (m *Metric) ParseNode(n ast.Node) { m.count(n, &m.counts) }
+
+// Parses node like `ParseNode`, but returns counts of node instead of
+// collecting them
+func (m *Metric) Count(n ast.Node) (c Counts) {
+	m.count(n, &c)
+	return
+}
+
+func (m *Metric) count(n ast.Node, c *Counts) {
+	if v, ok := n.(*ast.FuncDecl); ok {
+		if m.depths == nil {
+			m.depths = make(map[ast.Node]uint)
+		}
+		for construct, depth := range collect(v).constructs {
+			m.depths[construct] = depth
+		}
+	}
+	if depth, ok := m.depths[n]; ok {
+		c.add(depth)
+		delete(m.depths, n)
+	}
+}
+
+// Returns final score
+func (m Metric) Finish() float64 { return m.counts.Score(&m.Config) }
+
+// Returns raw counts collected so far
+func (m Metric) Counts() Counts { return m.counts }
+
+// Nesting statistics of a function
+type Depth struct {
+	// Maximum depth of nested blocks
+	Max uint `json:"max"`
+	// Average depth of statements
+	Average float64 `json:"average"`
+}
+
+// Collects nesting statistics of function, statements of function body have
+// depth 0, body of `if`, `for`, `switch`, `select` or function literal is one
+// level deeper than the construct itself
+func Measure(fd *ast.FuncDecl) Depth {
+	s := collect(fd)
+	var depth Depth
+	for _, d := range s.constructs {
+		if d > depth.Max {
+			depth.Max = d
+		}
+	}
+	if s.statements > 0 {
+		depth.Average = float64(s.depthSum) / float64(s.statements)
+	}
+	return depth
+}
+
+type stats struct {
+	// depths of bodies of nesting constructs
+	constructs map[ast.Node]uint
+	statements uint
+	depthSum   uint
+}
+
+func collect(fd *ast.FuncDecl) *stats {
+	s := &stats{constructs: make(map[ast.Node]uint)}
+	if fd.Body != nil {
+		ast.Walk(visitor{stats: s}, fd.Body)
+	}
+	return s
+}
+
+// Returns number of levels of construct beyond threshold
+func excessLevels(depth, threshold uint) float64 {
+	if depth <= threshold {
+		return 0
+	}
+	return float64(depth - threshold)
+}
+
+type visitor struct {
+	depth uint
+	stats *stats
+}
+
+func (v visitor) Visit(n ast.Node) ast.Visitor {
+	switch n.(type) {
+	case *ast.BlockStmt, *ast.CaseClause, *ast.CommClause:
+	case ast.Stmt:
+		v.stats.statements++
+		v.stats.depthSum += v.depth
+	}
+
+	inner := visitor{depth: v.depth + 1, stats: v.stats}
+	switch n := n.(type) {
+	case *ast.IfStmt:
+		v.stats.constructs[n] = inner.depth
+		walk(v, n.Init)
+		walk(v, n.Cond)
+		walk(inner, n.Body)
+		if elif, ok := n.Else.(*ast.IfStmt); ok {
+			// `else if` stays on the same level as its `if`
+			walk(v, elif)
+		} else {
+			walk(inner, n.Else)
+		}
+		return nil
+	case *ast.ForStmt:
+		v.stats.constructs[n] = inner.depth
+		walk(v, n.Init)
+		walk(v, n.Cond)
+		walk(v, n.Post)
+		walk(inner, n.Body)
+		return nil
+	case *ast.RangeStmt:
+		v.stats.constructs[n] = inner.depth
+		walk(v, n.X)
+		walk(inner, n.Body)
+		return nil
+	case *ast.SwitchStmt:
+		v.stats.constructs[n] = inner.depth
+		walk(v, n.Init)
+		walk(v, n.Tag)
+		walk(inner, n.Body)
+		return nil
+	case *ast.TypeSwitchStmt:
+		v.stats.constructs[n] = inner.depth
+		walk(v, n.Init)
+		walk(v, n.Assign)
+		walk(inner, n.Body)
+		return nil
+	case *ast.SelectStmt:
+		v.stats.constructs[n] = inner.depth
+		walk(inner, n.Body)
+		return nil
+	case *ast.FuncLit:
+		v.stats.constructs[n] = inner.depth
+		walk(inner, n.Body)
+		return nil
+	}
+	return v
+}
+
+// Walks optional node, ast.Walk does not accept nil
+func walk(v ast.Visitor, n ast.Node) {
+	if n != nil {
+		ast.Walk(v, n)
+	}
+}
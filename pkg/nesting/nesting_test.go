@@ -0,0 +1,77 @@
+package nesting
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func parseFunc(t *testing.T, src string) *ast.FuncDecl {
+	file, err := parser.ParseFile(token.NewFileSet(), "", "package main\n"+src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file.Decls[0].(*ast.FuncDecl)
+}
+
+func TestMeasure(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want Depth
+	}{
+		{"Empty", `func f() {}`, Depth{0, 0}},
+		{"Flat", `func f() { a := 1; _ = a }`, Depth{0, 0}},
+		{"If", `func f() { if true { g() } }`, Depth{1, 0.5}},
+		{"ElseIf", `func f() { if a { g() } else if b { g() } else { g() } }`, Depth{1, 0.6}},
+		{"Nested", `func f() {
+			for {
+				switch {
+				case true:
+					select {}
+				}
+			}
+			g()
+		}`, Depth{3, 3. / 4}},
+		{"FuncLit", `func f() { go func() { for range x { g() } }() }`, Depth{2, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Measure(parseFunc(t, tt.src)); got != tt.want {
+				t.Errorf("Measure() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetric(t *testing.T) {
+	src := `func f() {
+		if a {
+			for {
+				if b {
+					if c {}
+				}
+			}
+		}
+		if d {}
+	}`
+	m := Metric{Config: Weights{Threshold: 2, Level: 1.5}}
+	ast.Inspect(parseFunc(t, src), func(n ast.Node) bool {
+		m.ParseNode(n)
+		return true
+	})
+
+	// levels 3 and 4 exceed threshold by 1 and 2
+	if got, want := m.Finish(), 4.5; got != want {
+		t.Errorf("Finish() = %v, want %v", got, want)
+	}
+	if got, want := m.Counts().Depth, []float64{0, 2, 1, 1, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Counts().Depth = %v, want %v", got, want)
+	}
+	// threshold is applied when counts are scored
+	if got, want := m.Counts().Score(&Weights{Threshold: 1, Level: 1}), 6.; got != want {
+		t.Errorf("Score() with threshold 1 = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+// Package with `//gokys:` source directives which suppress or discount
+// measured effort of files, declarations and lines.
+//
+//	//gokys:ignore      effort is not counted
+//	//gokys:weight 0.5  effort is multiplied by weight
+//
+// Directive before package clause applies to the whole file. Directive on its
+// own line applies to the node on the next line (e.g. declaration with doc
+// comment or statement), directive after code applies to the node starting on
+// the same line.
+package pragma
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Prefix of all directives
+const Prefix = "//gokys:"
+
+// Weights of nodes with directives
+type Directives struct {
+	// weight of the whole file
+	file    float64
+	weights map[ast.Node]float64
+}
+
+// Finds directives of file and nodes they apply to
+func Parse(fset *token.FileSet, file *ast.File) (*Directives, error) {
+	d := &Directives{file: 1, weights: make(map[ast.Node]float64)}
+	for _, group := range file.Comments {
+		weight, found, err := groupWeight(fset, group)
+		if err != nil {
+			return nil, err
+		} else if !found {
+			continue
+		}
+
+		if group.End() < file.Package {
+			d.file *= weight
+			continue
+		}
+		target := findTarget(fset, file, group)
+		if target == nil {
+			pos := fset.Position(group.Pos())
+			return nil, fmt.Errorf("%s: directive does not apply to any code", pos)
+		}
+		if w, ok := d.weights[target]; ok {
+			weight *= w
+		}
+		d.weights[target] = weight
+	}
+	return d, nil
+}
+
+// Returns weight of the whole file, it applies to every node of file
+func (d *Directives) File() float64 {
+	if d == nil {
+		return 1
+	}
+	return d.file
+}
+
+// Returns weight of node if it has directive, weights of parent nodes and
+// the file are not included
+func (d *Directives) Weight(n ast.Node) (float64, bool) {
+	if d == nil {
+		return 1, false
+	}
+	w, ok := d.weights[n]
+	return w, ok
+}
+
+// Returns combined weight of directives in comment group
+func groupWeight(fset *token.FileSet, group *ast.CommentGroup) (weight float64, found bool, err error) {
+	weight = 1
+	for _, c := range group.List {
+		if !strings.HasPrefix(c.Text, Prefix) {
+			continue
+		}
+		found = true
+		fields := strings.Fields(strings.TrimPrefix(c.Text, Prefix))
+		switch {
+		case len(fields) == 1 && fields[0] == "ignore":
+			weight = 0
+		case len(fields) == 2 && fields[0] == "weight":
+			w, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || w < 0 || math.IsInf(w, 0) || math.IsNaN(w) {
+				return 0, false, fmt.Errorf("%s: weight should be a non-negative number, got %q", fset.Position(c.Pos()), fields[1])
+			}
+			weight *= w
+		default:
+			return 0, false, fmt.Errorf("%s: unknown directive %q", fset.Position(c.Pos()), c.Text)
+		}
+	}
+	return
+}
+
+// Returns outermost node which starts on the line of trailing comment group or
+// on the next line after comment group on its own line
+func findTarget(fset *token.FileSet, file *ast.File, group *ast.CommentGroup) ast.Node {
+	tf := fset.File(group.Pos())
+	line := tf.Line(group.Pos())
+	trailing := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if trailing || n == nil {
+			return false
+		}
+		if n.Pos() < group.Pos() && tf.Line(n.Pos()) == line {
+			if _, ok := n.(*ast.File); !ok {
+				trailing = true
+			}
+		}
+		return n.Pos() < group.Pos()
+	})
+	if !trailing {
+		line = tf.Line(group.End()) + 1
+	}
+
+	var target ast.Node
+	ast.Inspect(file, func(n ast.Node) bool {
+		if target != nil || n == nil {
+			return false
+		}
+		switch n.(type) {
+		case *ast.File, *ast.CommentGroup, *ast.Comment:
+			return true
+		}
+		if n.Pos().IsValid() && tf.Line(n.Pos()) == line {
+			target = n
+			return false
+		}
+		// nodes which end before target line can not contain it
+		return n.End().IsValid() && tf.Line(n.End()) >= line
+	})
+	return target
+}
@@ -0,0 +1,109 @@
+package pragma
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	src := `package p
+
+//gokys:weight 0.5
+func f() {
+	x := 1 //gokys:ignore
+	//gokys:weight 0.2
+	//gokys:weight 0.5
+	switch x {
+	}
+}
+
+// g is ignored
+//
+//gokys:ignore
+func g() {}
+
+var a = 1
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.File() != 1 {
+		t.Errorf("File() = %v, want 1", d.File())
+	}
+
+	f := file.Decls[0].(*ast.FuncDecl)
+	g := file.Decls[1].(*ast.FuncDecl)
+	tests := []struct {
+		node   ast.Node
+		weight float64
+		ok     bool
+	}{
+		{f, 0.5, true},
+		{f.Body.List[0], 0, true},
+		{f.Body.List[1], 0.1, true},
+		{g, 0, true},
+		{file.Decls[2], 1, false},
+		{f.Body, 1, false},
+	}
+	for i, tt := range tests {
+		w, ok := d.Weight(tt.node)
+		if ok != tt.ok || (ok && w != tt.weight) {
+			t.Errorf("%d: Weight() = %v, %v, want %v, %v", i, w, ok, tt.weight, tt.ok)
+		}
+	}
+}
+
+func TestParseFile(t *testing.T) {
+	src := `// Code copied from elsewhere.
+//gokys:ignore
+package p
+
+func f() {}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := Parse(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.File() != 0 {
+		t.Errorf("File() = %v, want 0", d.File())
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown":  "package p\n\n//gokys:skip\nfunc f() {}\n",
+		"weight":   "package p\n\n//gokys:weight -1\nfunc f() {}\n",
+		"noweight": "package p\n\n//gokys:weight\nfunc f() {}\n",
+		"nothing":  "package p\n\nfunc f() {}\n\n//gokys:ignore\n",
+	}
+	for name, src := range tests {
+		fset := token.NewFileSet()
+		file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Parse(fset, file); err == nil {
+			t.Errorf("%s: Parse() should fail", name)
+		}
+	}
+}
+
+func TestNil(t *testing.T) {
+	var d *Directives
+	if w, ok := d.Weight(&ast.Ident{}); w != 1 || ok || d.File() != 1 {
+		t.Errorf("nil directives should have weight 1")
+	}
+}
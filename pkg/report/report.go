@@ -0,0 +1,277 @@
+// Package report measures go files and combines results into per function,
+// file and package summaries.
+package report
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"sort"
+
+	"github.com/bragov4ik/go-kys/pkg/assets"
+	"github.com/bragov4ik/go-kys/pkg/doccov"
+	"github.com/bragov4ik/go-kys/pkg/loc"
+	"github.com/bragov4ik/go-kys/pkg/maintainability"
+	"github.com/bragov4ik/go-kys/pkg/nesting"
+	"github.com/bragov4ik/go-kys/pkg/pragma"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Results for a single function or method
+type Func struct {
+	// Name of function, methods are prefixed with receiver type
+	Name string `json:"name"`
+	// Line of function declaration
+	Line int `json:"line"`
+	// WMFP score in minutes
+	Score float64 `json:"score"`
+	// Minutes removed from score by `//gokys:` directives
+	Suppressed float64 `json:"suppressed"`
+	// Raw counts behind score
+	Counts wmfp.Counts `json:"counts"`
+	// Raw counts behind suppressed minutes, nil if nothing was suppressed
+	SuppressedCounts *wmfp.Counts `json:"suppressed_counts,omitempty"`
+	// Line counts including doc comment
+	Lines loc.Lines `json:"lines"`
+	// Score divided by number of source lines
+	MinutesPerSLOC float64 `json:"minutes_per_sloc"`
+	// Maintainability index of function
+	Maintainability maintainability.Index `json:"maintainability"`
+	// Depth of nested blocks
+	Nesting nesting.Depth `json:"nesting"`
+}
+
+// Results for a single file
+type File struct {
+	// Path to file
+	Path string `json:"path"`
+	// WMFP score in minutes including embedded files
+	Score float64 `json:"score"`
+	// Score of files embedded with `//go:embed`
+	AssetScore float64 `json:"asset_score"`
+	// Minutes removed from score by `//gokys:` directives
+	Suppressed float64 `json:"suppressed"`
+	// Raw counts behind score including embedded files
+	Counts wmfp.Counts `json:"counts"`
+	// Raw counts behind suppressed minutes, nil if nothing was suppressed
+	SuppressedCounts *wmfp.Counts `json:"suppressed_counts,omitempty"`
+	// Line counts of whole file
+	Lines loc.Lines `json:"lines"`
+	// Score divided by number of source lines
+	MinutesPerSLOC float64 `json:"minutes_per_sloc"`
+	// Maintainability index of whole file
+	Maintainability maintainability.Index `json:"maintainability"`
+	// Documentation coverage of exported identifiers
+	Docs doccov.Coverage `json:"docs"`
+	// Results of declared functions
+	Funcs []Func `json:"funcs"`
+	// Files embedded with `//go:embed`
+	Assets []assets.Asset `json:"assets"`
+}
+
+// Results for all measured files in a single directory
+type Package struct {
+	// Directory of package
+	Dir string `json:"dir"`
+	// Sum of file scores
+	Score float64 `json:"score"`
+	// Sum of scores of embedded files
+	AssetScore float64 `json:"asset_score"`
+	// Sum of minutes suppressed in files
+	Suppressed float64 `json:"suppressed"`
+	// Sum of file counts
+	Counts wmfp.Counts `json:"counts"`
+	// Sum of file line counts
+	Lines loc.Lines `json:"lines"`
+	// Score divided by number of source lines
+	MinutesPerSLOC float64 `json:"minutes_per_sloc"`
+	// Maintainability index of files averaged by their source lines
+	Maintainability maintainability.Index `json:"maintainability"`
+	// Documentation coverage of exported identifiers of all files
+	Docs doccov.Coverage `json:"docs"`
+	// Results of package files
+	Files []File `json:"files"`
+}
+
+// Results for all measured files
+type Report struct {
+	// Sum of package scores
+	Score float64 `json:"score"`
+	// Sum of scores of embedded files
+	AssetScore float64 `json:"asset_score"`
+	// Sum of minutes suppressed in packages
+	Suppressed float64 `json:"suppressed"`
+	// Sum of package counts
+	Counts wmfp.Counts `json:"counts"`
+	// Sum of package line counts
+	Lines loc.Lines `json:"lines"`
+	// Score divided by number of source lines
+	MinutesPerSLOC float64 `json:"minutes_per_sloc"`
+	// Maintainability index of packages averaged by their source lines
+	Maintainability maintainability.Index `json:"maintainability"`
+	// Documentation coverage of all packages, undocumented identifiers are
+	// listed only in packages
+	Docs doccov.Coverage `json:"docs"`
+	// Results of packages sorted by directory
+	Packages []Package `json:"packages"`
+}
+
+// Parses and measures file at given path
+func MeasureFile(path string, cfg *wmfp.Config) (File, error) {
+	return MeasureFileContext(context.Background(), path, cfg)
+}
+
+// Like `MeasureFile`, but stops when context is done
+func MeasureFileContext(ctx context.Context, path string, cfg *wmfp.Config) (File, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return File{}, err
+	}
+	return MeasureContext(ctx, fset, path, file, cfg)
+}
+
+// Measures already parsed file, it should be parsed with comments. Embedded
+// files are looked up relative to directory of `path`, they are weighted only
+// by directives of the whole file
+func Measure(fset *token.FileSet, path string, file *ast.File, cfg *wmfp.Config) (File, error) {
+	return MeasureContext(context.Background(), fset, path, file, cfg)
+}
+
+// Like `Measure`, but stops resolving embedded files when context is done
+func MeasureContext(ctx context.Context, fset *token.FileSet, path string, file *ast.File, cfg *wmfp.Config) (File, error) {
+	directives, err := pragma.Parse(fset, file)
+	if err != nil {
+		return File{}, err
+	}
+	test := doccov.IsTestFile(path)
+	measurer := wmfp.NewMeasurerWMFP(cfg)
+	measurer.Directives = directives
+	measurer.DocCov.TestFile = test
+	measurer.ParseFile(file)
+	counter := loc.NewCounter(fset, file)
+
+	embedded, err := assets.MeasureContext(ctx, file, filepath.Dir(path), &cfg.Assets)
+	if err != nil {
+		return File{}, err
+	}
+
+	assetCounts := wmfp.Counts{Assets: assets.Count(embedded)}
+	result := File{
+		Path:       path,
+		AssetScore: assets.Total(embedded) * directives.File(),
+		Counts:     measurer.Counts().Add(assetCounts.Scale(directives.File())),
+		Lines:      counter.File(),
+		Funcs:      []Func{},
+		Assets:     embedded,
+	}
+	result.Score = result.Counts.Score(cfg)
+	suppressed := measurer.SuppressedCounts().Add(assetCounts.Scale(1 - directives.File()))
+	if result.Suppressed = suppressed.Score(cfg); result.Suppressed != 0 {
+		result.SuppressedCounts = &suppressed
+	}
+	result.MinutesPerSLOC = result.Lines.PerSource(result.Score)
+	result.Maintainability = maintainability.Measure(file, result.Lines.Source)
+	result.Docs = doccov.NewCoverage(fset, measurer.DocCov.Symbols)
+
+	for _, decl := range file.Decls {
+		if fd, ok := decl.(*ast.FuncDecl); ok {
+			result.Funcs = append(result.Funcs, measureFunc(fset, fd, counter, cfg, directives, test))
+		}
+	}
+	return result, nil
+}
+
+func measureFunc(fset *token.FileSet, fd *ast.FuncDecl, counter *loc.Counter, cfg *wmfp.Config,
+	directives *pragma.Directives, test bool) Func {
+	measurer := wmfp.NewMeasurerWMFP(cfg)
+	measurer.Directives = directives
+	measurer.DocCov.TestFile = test
+	measurer.Parse(fd)
+
+	start := fd.Pos()
+	if fd.Doc != nil {
+		start = fd.Doc.Pos()
+	}
+
+	result := Func{
+		Name:   FuncName(fd),
+		Line:   fset.Position(fd.Pos()).Line,
+		Score:  measurer.Finish(),
+		Counts: measurer.Counts(),
+		Lines:  counter.Count(start, fd.End()),
+	}
+	suppressed := measurer.SuppressedCounts()
+	if result.Suppressed = suppressed.Score(cfg); result.Suppressed != 0 {
+		result.SuppressedCounts = &suppressed
+	}
+	result.MinutesPerSLOC = result.Lines.PerSource(result.Score)
+	result.Maintainability = maintainability.Measure(fd, result.Lines.Source)
+	result.Nesting = nesting.Measure(fd)
+	return result
+}
+
+// Returns name of function, methods are named like `T.Name` or `(*T).Name`
+func FuncName(fd *ast.FuncDecl) string {
+	if fd.Recv == nil || len(fd.Recv.List) == 0 {
+		return fd.Name.Name
+	}
+	return recvName(fd.Recv.List[0].Type) + "." + fd.Name.Name
+}
+
+func recvName(expr ast.Expr) string {
+	switch v := expr.(type) {
+	case *ast.StarExpr:
+		return "(*" + recvName(v.X) + ")"
+	case *ast.Ident:
+		return v.Name
+	case *ast.IndexExpr:
+		// generic receiver, type parameters are omitted
+		return recvName(v.X)
+	case *ast.ParenExpr:
+		return recvName(v.X)
+	}
+	return "?"
+}
+
+// Groups measured files by their directories
+func New(files []File) Report {
+	byDir := make(map[string][]File)
+	for _, file := range files {
+		dir := filepath.Dir(file.Path)
+		byDir[dir] = append(byDir[dir], file)
+	}
+
+	r := Report{Packages: []Package{}}
+	for dir, files := range byDir {
+		sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+		pkg := Package{Dir: dir, Files: files}
+		for _, file := range files {
+			pkg.Score += file.Score
+			pkg.AssetScore += file.AssetScore
+			pkg.Suppressed += file.Suppressed
+			pkg.Counts = pkg.Counts.Add(file.Counts)
+			pkg.Lines = pkg.Lines.Add(file.Lines)
+			pkg.Maintainability = pkg.Maintainability.Add(file.Maintainability)
+			pkg.Docs = pkg.Docs.Add(file.Docs)
+		}
+		pkg.MinutesPerSLOC = pkg.Lines.PerSource(pkg.Score)
+		r.Packages = append(r.Packages, pkg)
+	}
+	sort.Slice(r.Packages, func(i, j int) bool { return r.Packages[i].Dir < r.Packages[j].Dir })
+
+	for _, pkg := range r.Packages {
+		r.Score += pkg.Score
+		r.AssetScore += pkg.AssetScore
+		r.Suppressed += pkg.Suppressed
+		r.Counts = r.Counts.Add(pkg.Counts)
+		r.Lines = r.Lines.Add(pkg.Lines)
+		r.Maintainability = r.Maintainability.Add(pkg.Maintainability)
+		r.Docs = r.Docs.Add(pkg.Docs)
+	}
+	r.Docs.Undocumented = nil
+	r.MinutesPerSLOC = r.Lines.PerSource(r.Score)
+	return r
+}
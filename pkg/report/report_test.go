@@ -0,0 +1,176 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/codestruct"
+	"github.com/bragov4ik/go-kys/pkg/loc"
+	"github.com/bragov4ik/go-kys/pkg/maintainability"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+func parse(t *testing.T, path, src string) File {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := wmfp.Config{CodeStructComp: codestruct.Weights{Func: 1, Struct: 1}}
+	result, err := Measure(fset, path, file, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMeasure(t *testing.T) {
+	src := `package main
+
+type T struct{}
+
+// Method doc
+func (t *T) M() {}
+
+func f() {
+}
+`
+	got := parse(t, "a/main.go", src)
+
+	// cyclomatic complexity starts from 1 for every function
+	if got.Score != 5 {
+		t.Errorf("Score = %v, want 5", got.Score)
+	}
+	if want := (loc.Lines{Physical: 9, Source: 5, Comment: 1, Blank: 3}); got.Lines != want {
+		t.Errorf("Lines = %+v, want %+v", got.Lines, want)
+	}
+	if got.MinutesPerSLOC != 1 {
+		t.Errorf("MinutesPerSLOC = %v, want 1", got.MinutesPerSLOC)
+	}
+
+	want := []Func{
+		{Name: "(*T).M", Line: 6, Score: 2, Lines: loc.Lines{Physical: 2, Source: 1, Comment: 1}, MinutesPerSLOC: 2},
+		{Name: "f", Line: 8, Score: 2, Lines: loc.Lines{Physical: 2, Source: 2}, MinutesPerSLOC: 1},
+	}
+	if len(got.Funcs) != len(want) {
+		t.Fatalf("Funcs = %+v, want %+v", got.Funcs, want)
+	}
+	for i := range want {
+		mi := got.Funcs[i].Maintainability
+		if mi.Complexity != 1 || mi.SLOC != want[i].Lines.Source {
+			t.Errorf("Funcs[%v].Maintainability = %+v, want complexity 1 and %v SLOC", i, mi, want[i].Lines.Source)
+		}
+		got.Funcs[i].Maintainability = maintainability.Index{}
+		counts := got.Funcs[i].Counts
+		if counts.CycloComp.Funcs != 1 || counts.CodeStructComp.Func != 1 || counts.Halstead == 0 {
+			t.Errorf("Funcs[%v].Counts = %+v, want one function", i, counts)
+		}
+		got.Funcs[i].Counts = wmfp.Counts{}
+		if !reflect.DeepEqual(got.Funcs[i], want[i]) {
+			t.Errorf("Funcs[%v] = %+v, want %+v", i, got.Funcs[i], want[i])
+		}
+	}
+}
+
+func TestMeasureDirectives(t *testing.T) {
+	src := `package main
+
+//gokys:ignore
+func f() {
+}
+
+func g() {
+	type T struct{} //gokys:weight 0.5
+}
+`
+	got := parse(t, "a/main.go", src)
+
+	// f is ignored, struct in g is halved
+	if got.Score != 2.5 || got.Suppressed != 2.5 {
+		t.Errorf("Score, Suppressed = %v, %v, want 2.5, 2.5", got.Score, got.Suppressed)
+	}
+	if got.Funcs[0].Score != 0 || got.Funcs[0].Suppressed != 2 {
+		t.Errorf("Funcs[0] = %+v, want score 0 and 2 suppressed", got.Funcs[0])
+	}
+	if got.Funcs[1].Score != 2.5 || got.Funcs[1].Suppressed != 0.5 {
+		t.Errorf("Funcs[1] = %+v, want score 2.5 and 0.5 suppressed", got.Funcs[1])
+	}
+	if r := New([]File{got}); r.Suppressed != 2.5 || r.Packages[0].Suppressed != 2.5 {
+		t.Errorf("Report suppressed = %v, want 2.5", r.Suppressed)
+	}
+}
+
+func TestMeasureContext(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n\n//go:embed data\nvar d string\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "data", "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := MeasureFileContext(ctx, path, &wmfp.Config{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("MeasureFileContext() = %v, want canceled", err)
+	}
+	if result, err := MeasureFileContext(context.Background(), path, &wmfp.Config{}); err != nil || len(result.Assets) != 1 {
+		t.Errorf("MeasureFileContext() = %+v, %v, want one embedded file", result.Assets, err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	files := []File{
+		{Path: "b/x.go", Score: 1, Lines: loc.Lines{Physical: 1, Source: 1}, Maintainability: maintainability.Compute(1, 1, 1)},
+		{Path: "a/y.go", Score: 2, Lines: loc.Lines{Physical: 2, Source: 1, Blank: 1}, Maintainability: maintainability.Compute(100, 5, 1)},
+		{Path: "a/x.go", Score: 3, Lines: loc.Lines{Physical: 1, Source: 1}, Maintainability: maintainability.Compute(1, 1, 1)},
+	}
+	r := New(files)
+
+	if r.Score != 6 || r.Lines.Source != 3 || r.MinutesPerSLOC != 2 {
+		t.Errorf("totals = %v, %+v, %v, want 6, 3 SLOC, 2", r.Score, r.Lines, r.MinutesPerSLOC)
+	}
+	if len(r.Packages) != 2 {
+		t.Fatalf("len(Packages) = %v, want 2", len(r.Packages))
+	}
+	a := r.Packages[0]
+	if a.Dir != "a" || a.Score != 5 || a.Files[0].Path != "a/x.go" || a.MinutesPerSLOC != 2.5 {
+		t.Errorf("Packages[0] = %+v, want dir a with score 5 and sorted files", a)
+	}
+	if a.Maintainability.SLOC != 2 || r.Maintainability.SLOC != 3 {
+		t.Errorf("Maintainability SLOC = %v, %v, want 2, 3", a.Maintainability.SLOC, r.Maintainability.SLOC)
+	}
+	if mi := a.Maintainability.Value; mi <= files[1].Maintainability.Value || mi >= files[2].Maintainability.Value {
+		t.Errorf("Packages[0] index = %v, want between indices of its files", mi)
+	}
+}
+
+func TestFuncName(t *testing.T) {
+	src := `package main
+	func f() {}
+	func (T) f() {}
+	func (t *T) f() {}
+	func (t *G[K]) f() {}`
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"f", "T.f", "(*T).f", "(*G).f"}
+	for i, decl := range file.Decls {
+		if got := FuncName(decl.(*ast.FuncDecl)); got != want[i] {
+			t.Errorf("FuncName(%v) = %v, want %v", i, got, want[i])
+		}
+	}
+}
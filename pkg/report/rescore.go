@@ -0,0 +1,59 @@
+package report
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Reads report written by `WriteJSON`
+func ReadJSON(r io.Reader) (Report, error) {
+	var report Report
+	err := json.NewDecoder(r).Decode(&report)
+	return report, err
+}
+
+// Recomputes scores of all functions, files and packages from their raw counts
+// with config returned by `cfg` for path of every file. Line counts, indices
+// and documentation coverage do not depend on weights and are kept
+func Rescore(r Report, cfg func(path string) wmfp.Config) Report {
+	var files []File
+	for _, pkg := range r.Packages {
+		for _, file := range pkg.Files {
+			c := cfg(file.Path)
+			files = append(files, rescoreFile(file, &c))
+		}
+	}
+	return New(files)
+}
+
+func rescoreFile(file File, cfg *wmfp.Config) File {
+	file.Score = file.Counts.Score(cfg)
+	file.AssetScore = file.Counts.Assets.Score(&cfg.Assets)
+	file.Suppressed = scoreSuppressed(file.SuppressedCounts, cfg)
+	file.MinutesPerSLOC = file.Lines.PerSource(file.Score)
+
+	assets := append(file.Assets[:0:0], file.Assets...)
+	for i, asset := range assets {
+		assets[i].Score = asset.Counts().Score(&cfg.Assets)
+	}
+	file.Assets = assets
+
+	funcs := make([]Func, len(file.Funcs))
+	for i, fn := range file.Funcs {
+		fn.Score = fn.Counts.Score(cfg)
+		fn.Suppressed = scoreSuppressed(fn.SuppressedCounts, cfg)
+		fn.MinutesPerSLOC = fn.Lines.PerSource(fn.Score)
+		funcs[i] = fn
+	}
+	file.Funcs = funcs
+	return file
+}
+
+func scoreSuppressed(counts *wmfp.Counts, cfg *wmfp.Config) float64 {
+	if counts == nil {
+		return 0
+	}
+	return counts.Score(cfg)
+}
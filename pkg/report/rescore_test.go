@@ -0,0 +1,93 @@
+package report
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/assets"
+	"github.com/bragov4ik/go-kys/pkg/comments"
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/inline"
+	"github.com/bragov4ik/go-kys/pkg/nesting"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+func TestRescore(t *testing.T) {
+	dir := t.TempDir()
+	src := `package p
+
+import _ "embed"
+
+//go:embed query.sql
+var query string
+
+// Counts positive numbers
+func f(xs []int) (n int) {
+	for _, x := range xs {
+		if x > 0 { //gokys:weight 0.5
+			n++
+		}
+	}
+	return
+}
+`
+	path := filepath.Join(dir, "p.go")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "query.sql"), []byte("SELECT 1;\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	first := wmfp.Config{CycloComp: cyclo.Weights{If: 1}, Halstead: 1}
+	second := wmfp.Config{
+		CycloComp:  cyclo.Weights{If: 2, Rng: 1},
+		Comment:    comments.Weights{Word: 0.5},
+		InlineData: inline.Weights{Int: 0.3, String: 0.1},
+		Halstead:   0.1,
+		Assets:     assets.Weights{SQL: assets.Kind{Byte: 0.1, Line: 1}},
+		// first config scans without nesting threshold
+		Nesting: nesting.Weights{Threshold: 1, Level: 2},
+	}
+	measure := func(cfg *wmfp.Config) Report {
+		file, err := MeasureFile(path, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return New([]File{file})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, measure(&first)); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Rescore(saved, func(string) wmfp.Config { return second })
+	want := measure(&second)
+
+	near := func(name string, got, want float64) {
+		if math.Abs(got-want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", name, got, want)
+		}
+	}
+	near("Score", got.Score, want.Score)
+	near("AssetScore", got.AssetScore, want.AssetScore)
+	near("Suppressed", got.Suppressed, want.Suppressed)
+	near("MinutesPerSLOC", got.MinutesPerSLOC, want.MinutesPerSLOC)
+	gotFile, wantFile := got.Packages[0].Files[0], want.Packages[0].Files[0]
+	near("Assets[0].Score", gotFile.Assets[0].Score, wantFile.Assets[0].Score)
+	near("Funcs[0].Score", gotFile.Funcs[0].Score, wantFile.Funcs[0].Score)
+	near("Funcs[0].Suppressed", gotFile.Funcs[0].Suppressed, wantFile.Funcs[0].Suppressed)
+	if want.Suppressed == 0 || want.AssetScore == 0 {
+		t.Errorf("test should cover suppressed and embedded files")
+	}
+	if saved.Packages[0].Files[0].Assets[0].Score != 0 {
+		t.Errorf("Rescore() modified saved report")
+	}
+}
@@ -0,0 +1,132 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/bragov4ik/go-kys/pkg/loc"
+	"github.com/bragov4ik/go-kys/pkg/maintainability"
+)
+
+// Output formats supported by `Write`
+var Formats = map[string]func(io.Writer, Report) error{
+	"total": WriteTotal,
+	"text":  WriteText,
+	"json":  WriteJSON,
+}
+
+// Writes report in one of `Formats`
+func Write(w io.Writer, r Report, format string) error {
+	write, ok := Formats[format]
+	if !ok {
+		return fmt.Errorf("unknown report format %q", format)
+	}
+	return write(w, r)
+}
+
+// Writes only total score
+func WriteTotal(w io.Writer, r Report) error {
+	_, err := fmt.Fprintln(w, r.Score)
+	return err
+}
+
+// Writes indented table with packages, files and functions
+func WriteText(w io.Writer, r Report) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tMINUTES\tLOC\tSLOC\tCLOC\tBLANK\tMIN/SLOC\tMI\tDEPTH\t")
+	for _, pkg := range r.Packages {
+		writeRow(tw, "package "+pkg.Dir, pkg.Score, pkg.Lines, pkg.MinutesPerSLOC, pkg.Maintainability, "")
+		for _, file := range pkg.Files {
+			writeRow(tw, "  file "+file.Path, file.Score, file.Lines, file.MinutesPerSLOC, file.Maintainability, "")
+			for _, fn := range file.Funcs {
+				depth := fmt.Sprintf("%d/%.1f", fn.Nesting.Max, fn.Nesting.Average)
+				writeRow(tw, "    func "+fn.Name, fn.Score, fn.Lines, fn.MinutesPerSLOC, fn.Maintainability, depth)
+			}
+		}
+	}
+	writeRow(tw, "total", r.Score, r.Lines, r.MinutesPerSLOC, r.Maintainability, "")
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if err := writeDocs(tw, r); err != nil {
+		return err
+	}
+	if err := writeAssets(tw, r); err != nil {
+		return err
+	}
+	return writeSuppressed(tw, r)
+}
+
+func writeDocs(tw *tabwriter.Writer, r Report) error {
+	if r.Docs.Exported == 0 {
+		return nil
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "DOCUMENTATION\tDOCUMENTED/EXPORTED\tCOVERAGE\t")
+	for _, pkg := range r.Packages {
+		if pkg.Docs.Exported == 0 {
+			continue
+		}
+		fmt.Fprintf(tw, "package %s\t%d/%d\t%.1f%%\t\n", pkg.Dir, pkg.Docs.Documented, pkg.Docs.Exported, pkg.Docs.Percent)
+		for _, missing := range pkg.Docs.Undocumented {
+			fmt.Fprintf(tw, "  %s %s\t%s:%d\t\t\n", missing.Kind, missing.Name, missing.Path, missing.Line)
+		}
+	}
+	fmt.Fprintf(tw, "total\t%d/%d\t%.1f%%\t\n", r.Docs.Documented, r.Docs.Exported, r.Docs.Percent)
+	return tw.Flush()
+}
+
+func writeAssets(tw *tabwriter.Writer, r Report) error {
+	if r.AssetScore == 0 {
+		return nil
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "EMBEDDED\tPATTERN\tBYTES\tLINES\tMINUTES\t")
+	for _, pkg := range r.Packages {
+		for _, file := range pkg.Files {
+			for _, asset := range file.Assets {
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.2f\t\n", asset.Path, asset.Pattern, asset.Bytes, asset.Lines, asset.Score)
+			}
+		}
+	}
+	fmt.Fprintf(tw, "total\t\t\t\t%.2f\t\n", r.AssetScore)
+	return tw.Flush()
+}
+
+func writeSuppressed(tw *tabwriter.Writer, r Report) error {
+	if r.Suppressed == 0 {
+		return nil
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "SUPPRESSED\tMINUTES\t")
+	for _, pkg := range r.Packages {
+		for _, file := range pkg.Files {
+			if file.Suppressed == 0 {
+				continue
+			}
+			fmt.Fprintf(tw, "file %s\t%.2f\t\n", file.Path, file.Suppressed)
+			for _, fn := range file.Funcs {
+				if fn.Suppressed != 0 {
+					fmt.Fprintf(tw, "  func %s\t%.2f\t\n", fn.Name, fn.Suppressed)
+				}
+			}
+		}
+	}
+	fmt.Fprintf(tw, "total\t%.2f\t\n", r.Suppressed)
+	return tw.Flush()
+}
+
+func writeRow(w io.Writer, name string, score float64, lines loc.Lines, perSLOC float64,
+	mi maintainability.Index, depth string) {
+	fmt.Fprintf(w, "%s\t%.2f\t%d\t%d\t%d\t%d\t%.2f\t%.1f\t%s\t\n", name, score,
+		lines.Physical, lines.Source, lines.Comment, lines.Blank, perSLOC, mi.Normalized, depth)
+}
+
+// Writes report as indented json
+func WriteJSON(w io.Writer, r Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
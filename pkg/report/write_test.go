@@ -0,0 +1,51 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/assets"
+	"github.com/bragov4ik/go-kys/pkg/doccov"
+	"github.com/bragov4ik/go-kys/pkg/loc"
+)
+
+func TestWrite(t *testing.T) {
+	r := New([]File{{
+		Path:       "a/x.go",
+		Score:      4,
+		AssetScore: 1,
+		Suppressed: 0.5,
+		Lines:      loc.Lines{Physical: 3, Source: 2, Blank: 1},
+		Funcs:      []Func{{Name: "f", Line: 1, Score: 3, Suppressed: 0.5, Lines: loc.Lines{Physical: 2, Source: 2}}},
+		Assets:     []assets.Asset{{Pattern: "*.sql", Path: "a/q.sql", Bytes: 10, Lines: 1, Score: 1}},
+		Docs: doccov.Coverage{Exported: 2, Documented: 1, Percent: 50,
+			Undocumented: []doccov.Missing{{Kind: "func", Name: "F", Path: "a/x.go", Line: 2}}},
+	}})
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"total", []string{"4\n"}},
+		{"text", []string{"package a", "file a/x.go", "func f", "total", "2.00", "EMBEDDED", "a/q.sql", "50.0%", "func F", "a/x.go:2", "SUPPRESSED", "0.50"}},
+		{"json", []string{`"dir": "a"`, `"name": "f"`, `"source": 2`, `"minutes_per_sloc": 2`, `"pattern": "*.sql"`, `"percent": 50`, `"suppressed": 0.5`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, r, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("Write(%v) = %q, should contain %q", tt.format, buf.String(), want)
+				}
+			}
+		})
+	}
+
+	if err := Write(&bytes.Buffer{}, r, "yaml"); err == nil {
+		t.Error("Write(yaml) should fail")
+	}
+}
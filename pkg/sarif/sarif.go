@@ -0,0 +1,210 @@
+// Package sarif writes effort of functions and violations of effort budgets
+// as SARIF 2.1.0 log for code scanning tools.
+package sarif
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+// Version and schema of written logs
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Budgets of effort in minutes, 0 disables a budget
+type Budgets struct {
+	// Budget of every function
+	Func float64
+	// Budget of every file
+	File float64
+	// Budget of every package
+	Package float64
+}
+
+// Rule of every metric, ids are names of metrics in config
+var rules = []rule{
+	newRule("cyclomatic", "CyclomaticComplexity", "Effort of independent paths through functions: if, for, range, case, && and ||."),
+	newRule("comment", "Comments", "Effort of writing words of comments."),
+	newRule("codestruct", "CodeStructure", "Effort of declaring functions, their signatures, structures and interfaces."),
+	newRule("inline", "InlineData", "Effort of hard-coded literals, strings and composite literals."),
+	newRule("arithmetic", "ArithmeticIntricacy", "Effort of arithmetic operators."),
+	newRule("halstead", "HalsteadVolume", "Effort of operators and operands measured as Halstead volume."),
+	newRule("nesting", "NestingDepth", "Effort of keeping deeply nested code in mind."),
+	newRule("doccov", "DocumentationCoverage", "Effort of writing missing documentation of exported identifiers."),
+	newRule("embed", "EmbeddedFiles", "Effort of files embedded with //go:embed."),
+}
+
+// Writes log with a result for every function with effort, attributed to the
+// metric which adds the most minutes. Functions above budget are warnings,
+// the rest are notes. Files and packages are reported only above budget,
+// package results point to its first file
+func Write(w io.Writer, r report.Report, cfg func(path string) wmfp.Config, budgets Budgets) error {
+	out := run{
+		Tool:    tool{Driver: driver{Name: "gokys", InformationURI: "https://github.com/bragov4ik/go-kys", Rules: rules}},
+		Results: []result{},
+	}
+	for _, pkg := range r.Packages {
+		var pkgBreakdown wmfp.Breakdown
+		for _, file := range pkg.Files {
+			config := cfg(file.Path)
+			fileBreakdown := file.Counts.Breakdown(&config)
+			pkgBreakdown = pkgBreakdown.Add(fileBreakdown)
+			for _, fn := range file.Funcs {
+				if fn.Score == 0 {
+					continue
+				}
+				breakdown := fn.Counts.Breakdown(&config)
+				res := newResult(fmt.Sprintf("func %s takes %.2f minutes", fn.Name, fn.Score),
+					file.Path, fn.Line, fn.Score, fn.Suppressed, breakdown.Parts(), budgets.Func)
+				res.PartialFingerprints = map[string]string{"function": file.Path + ":" + fn.Name}
+				out.Results = append(out.Results, res)
+			}
+			if budgets.File > 0 && file.Score > budgets.File {
+				out.Results = append(out.Results, newResult(fmt.Sprintf("file %s takes %.2f minutes", file.Path, file.Score),
+					file.Path, 1, file.Score, file.Suppressed, fileBreakdown.Parts(), budgets.File))
+			}
+		}
+		if budgets.Package > 0 && pkg.Score > budgets.Package && len(pkg.Files) > 0 {
+			out.Results = append(out.Results, newResult(fmt.Sprintf("package %s takes %.2f minutes", pkg.Dir, pkg.Score),
+				pkg.Files[0].Path, 1, pkg.Score, pkg.Suppressed, pkgBreakdown.Parts(), budgets.Package))
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log{Version: Version, Schema: Schema, Runs: []run{out}})
+}
+
+// Creates result of function, file or package, it is a warning if score is
+// above non zero budget. Parts come from `wmfp.Breakdown.Parts`, which has a
+// part for every metric, so they are never empty and the top one is the rule
+func newResult(text, path string, line int, score, suppressed float64, parts []wmfp.Part, budget float64) result {
+	breakdown := make(map[string]float64, len(parts))
+	for _, part := range parts {
+		breakdown[part.Name] = part.Minutes
+	}
+	sorted := append([]wmfp.Part{}, parts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Minutes > sorted[j].Minutes })
+	top := sorted[0]
+
+	res := result{
+		RuleID: top.Name,
+		Level:  "note",
+		Locations: []location{{PhysicalLocation: physicalLocation{
+			ArtifactLocation: artifactLocation(path),
+			Region:           region{StartLine: line},
+		}}},
+		Properties: properties{Score: score, Suppressed: suppressed, Breakdown: breakdown},
+	}
+	if budget > 0 {
+		res.Properties.Budget = budget
+		if score > budget {
+			res.Level = "warning"
+			text += fmt.Sprintf(", budget is %g", budget)
+		}
+	}
+	res.Message = message{Text: fmt.Sprintf("%s, mostly %s (%.2f)", text, top.Name, top.Minutes)}
+	return res
+}
+
+// Returns location of file, relative paths are relative to source root
+func artifactLocation(path string) artifact {
+	if filepath.IsAbs(path) {
+		return artifact{URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()}
+	}
+	return artifact{URI: (&url.URL{Path: filepath.ToSlash(filepath.Clean(path))}).String(), URIBaseID: "%SRCROOT%"}
+}
+
+// Subset of SARIF 2.1.0 used by `Write`
+type log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []run  `json:"runs"`
+}
+
+type run struct {
+	Tool    tool     `json:"tool"`
+	Results []result `json:"results"`
+}
+
+type tool struct {
+	Driver driver `json:"driver"`
+}
+
+type driver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+	Rules          []rule `json:"rules"`
+}
+
+type rule struct {
+	ID                   string        `json:"id"`
+	Name                 string        `json:"name"`
+	ShortDescription     message       `json:"shortDescription"`
+	DefaultConfiguration configuration `json:"defaultConfiguration"`
+}
+
+func newRule(id, name, description string) rule {
+	return rule{
+		ID:                   id,
+		Name:                 name,
+		ShortDescription:     message{Text: description},
+		DefaultConfiguration: configuration{Level: "note"},
+	}
+}
+
+type configuration struct {
+	Level string `json:"level"`
+}
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type result struct {
+	RuleID              string            `json:"ruleId"`
+	Level               string            `json:"level"`
+	Message             message           `json:"message"`
+	Locations           []location        `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	Properties          properties        `json:"properties"`
+}
+
+type location struct {
+	PhysicalLocation physicalLocation `json:"physicalLocation"`
+}
+
+type physicalLocation struct {
+	ArtifactLocation artifact `json:"artifactLocation"`
+	Region           region   `json:"region"`
+}
+
+type artifact struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type region struct {
+	StartLine int `json:"startLine"`
+}
+
+// Property bag of result
+type properties struct {
+	// WMFP score in minutes
+	Score float64 `json:"score"`
+	// Minutes removed from score by `//gokys:` directives
+	Suppressed float64 `json:"suppressed"`
+	// Budget of score, omitted if there is none
+	Budget float64 `json:"budget,omitempty"`
+	// Minutes of every metric
+	Breakdown map[string]float64 `json:"breakdown"`
+}
@@ -0,0 +1,120 @@
+package sarif
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+func TestWrite(t *testing.T) {
+	cfg := wmfp.Config{CycloComp: cyclo.Weights{If: 2}, Halstead: 0.1}
+	counts := func(ifs, halstead float64) wmfp.Counts {
+		return wmfp.Counts{CycloComp: cyclo.Counts{If: ifs}, Halstead: halstead}
+	}
+	file := func(path string, funcs ...report.Func) report.File {
+		f := report.File{Path: path, Funcs: funcs}
+		for _, fn := range funcs {
+			f.Counts = f.Counts.Add(fn.Counts)
+		}
+		f.Score = f.Counts.Score(&cfg)
+		return f
+	}
+	r := report.New([]report.File{
+		file("a/a.go",
+			report.Func{Name: "big", Line: 3, Score: 10, Counts: counts(5, 0)},
+			report.Func{Name: "empty", Line: 9},
+		),
+		file("a/b.go", report.Func{Name: "T.small", Line: 5, Score: 3, Counts: counts(1, 10)}),
+		file("/abs/c.go", report.Func{Name: "tiny", Line: 1, Score: 1, Counts: counts(0, 10)}),
+	})
+
+	var b strings.Builder
+	if err := Write(&b, r, func(string) wmfp.Config { return cfg }, Budgets{Func: 5, File: 9, Package: 12}); err != nil {
+		t.Fatal(err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID  string `json:"ruleId"`
+				Level   string `json:"level"`
+				Message struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI       string `json:"uri"`
+							URIBaseID string `json:"uriBaseId"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+				Properties struct {
+					Score     float64            `json:"score"`
+					Breakdown map[string]float64 `json:"breakdown"`
+				} `json:"properties"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(b.String()), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Tool.Driver.Rules) != len(rules) {
+		t.Fatalf("Write() = %s", b.String())
+	}
+
+	want := []struct {
+		rule    string
+		level   string
+		message string
+		uri     string
+		line    int
+	}{
+		{"halstead", "note", "func tiny takes 1.00 minutes, mostly halstead (1.00)", "file:///abs/c.go", 1},
+		{"cyclomatic", "warning", "func big takes 10.00 minutes, budget is 5, mostly cyclomatic (10.00)", "a/a.go", 3},
+		{"cyclomatic", "warning", "file a/a.go takes 10.00 minutes, budget is 9", "a/a.go", 1},
+		{"cyclomatic", "note", "func T.small takes 3.00 minutes, mostly cyclomatic (2.00)", "a/b.go", 5},
+		{"cyclomatic", "warning", "package a takes 13.00 minutes, budget is 12, mostly cyclomatic (12.00)", "a/a.go", 1},
+	}
+	results := log.Runs[0].Results
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %s", len(results), len(want), b.String())
+	}
+	for i, w := range want {
+		got := results[i]
+		loc := got.Locations[0].PhysicalLocation
+		if got.RuleID != w.rule || got.Level != w.level || !strings.HasPrefix(got.Message.Text, w.message) ||
+			loc.ArtifactLocation.URI != w.uri || loc.Region.StartLine != w.line {
+			t.Errorf("result %d = %+v, want %+v", i, got, w)
+		}
+	}
+	if got := results[3].Properties.Breakdown; got["cyclomatic"] != 2 || got["halstead"] != 1 {
+		t.Errorf("breakdown = %v, want cyclomatic 2 and halstead 1", got)
+	}
+}
+
+func TestRules(t *testing.T) {
+	parts := (&wmfp.Breakdown{}).Parts()
+	if len(parts) != len(rules) {
+		t.Fatalf("got %d parts, want %d", len(parts), len(rules))
+	}
+	for i, part := range parts {
+		if rules[i].ID != part.Name {
+			t.Errorf("rule %d = %s, want %s", i, rules[i].ID, part.Name)
+		}
+	}
+}
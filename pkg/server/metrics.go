@@ -0,0 +1,134 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Upper bounds of request duration histogram in seconds
+var durationBuckets = []float64{0.005, 0.01, 0.05, 0.1, 0.5, 1, 5, 10, 30, 60}
+
+// Metrics of server in Prometheus text format
+type metrics struct {
+	mu sync.Mutex
+	// requests by handler and status code
+	requests map[[2]string]uint64
+	// request durations by handler
+	durations map[string]*histogram
+	inFlight  int64
+	files     uint64
+	bytes     uint64
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newMetrics() *metrics {
+	return &metrics{requests: make(map[[2]string]uint64), durations: make(map[string]*histogram)}
+}
+
+// Wraps handler counting its requests and their durations
+func (m *metrics) instrument(name string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		m.inFlight++
+		m.mu.Unlock()
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		elapsed := time.Since(start).Seconds()
+
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		m.inFlight--
+		m.requests[[2]string{name, strconv.Itoa(rec.status)}]++
+		hist, ok := m.durations[name]
+		if !ok {
+			hist = &histogram{counts: make([]uint64, len(durationBuckets))}
+			m.durations[name] = hist
+		}
+		for i, bound := range durationBuckets {
+			if elapsed <= bound {
+				hist.counts[i]++
+			}
+		}
+		hist.sum += elapsed
+		hist.count++
+	}
+}
+
+// Counts measured files and bytes of uploaded sources
+func (m *metrics) analyzed(files int, bytes int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files += uint64(files)
+	m.bytes += uint64(bytes)
+}
+
+// Writes all metrics in Prometheus text exposition format
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP gokys_http_requests_total Requests handled by server.")
+	fmt.Fprintln(w, "# TYPE gokys_http_requests_total counter")
+	keys := make([][2]string, 0, len(m.requests))
+	for key := range m.requests {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	for _, key := range keys {
+		fmt.Fprintf(w, "gokys_http_requests_total{handler=%q,code=%q} %d\n", key[0], key[1], m.requests[key])
+	}
+
+	fmt.Fprintln(w, "# HELP gokys_http_request_duration_seconds Duration of requests.")
+	fmt.Fprintln(w, "# TYPE gokys_http_request_duration_seconds histogram")
+	names := make([]string, 0, len(m.durations))
+	for name := range m.durations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		hist := m.durations[name]
+		for i, bound := range durationBuckets {
+			fmt.Fprintf(w, "gokys_http_request_duration_seconds_bucket{handler=%q,le=%q} %d\n",
+				name, strconv.FormatFloat(bound, 'g', -1, 64), hist.counts[i])
+		}
+		fmt.Fprintf(w, "gokys_http_request_duration_seconds_bucket{handler=%q,le=\"+Inf\"} %d\n", name, hist.count)
+		fmt.Fprintf(w, "gokys_http_request_duration_seconds_sum{handler=%q} %g\n", name, hist.sum)
+		fmt.Fprintf(w, "gokys_http_request_duration_seconds_count{handler=%q} %d\n", name, hist.count)
+	}
+
+	fmt.Fprintln(w, "# HELP gokys_http_requests_in_flight Requests being handled.")
+	fmt.Fprintln(w, "# TYPE gokys_http_requests_in_flight gauge")
+	fmt.Fprintf(w, "gokys_http_requests_in_flight %d\n", m.inFlight)
+	fmt.Fprintln(w, "# HELP gokys_analyzed_files_total Go files measured by analyze requests.")
+	fmt.Fprintln(w, "# TYPE gokys_analyzed_files_total counter")
+	fmt.Fprintf(w, "gokys_analyzed_files_total %d\n", m.files)
+	fmt.Fprintln(w, "# HELP gokys_analyzed_bytes_total Bytes of sources and archives uploaded to analyze.")
+	fmt.Fprintln(w, "# TYPE gokys_analyzed_bytes_total counter")
+	_, err := fmt.Fprintf(w, "gokys_analyzed_bytes_total %d\n", m.bytes)
+	return err
+}
+
+// Remembers status code written by handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	s := New(Options{})
+	do(s, http.MethodPost, "/v1/analyze?name=a.go", []byte(source[:strings.Index(source, "import")]+"func f() {}\n"))
+	do(s, http.MethodPost, "/v1/analyze?config=missing", nil)
+	do(s, http.MethodGet, "/healthz", nil)
+
+	body := do(s, http.MethodGet, "/metrics", nil).Body.String()
+	for _, want := range []string{
+		`gokys_http_requests_total{handler="POST /v1/analyze",code="200"} 1`,
+		`gokys_http_requests_total{handler="POST /v1/analyze",code="400"} 1`,
+		`gokys_http_requests_total{handler="GET /healthz",code="200"} 1`,
+		`gokys_http_request_duration_seconds_bucket{handler="POST /v1/analyze",le="+Inf"} 2`,
+		`gokys_http_request_duration_seconds_count{handler="GET /healthz"} 1`,
+		"gokys_http_requests_in_flight 1",
+		"gokys_analyzed_files_total 1",
+		"# TYPE gokys_analyzed_bytes_total counter",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics should contain %q, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package server exposes measuring of go sources and validation of configs
+// over HTTP.
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/report"
+)
+
+// Name of config used when request does not choose one
+const DefaultConfig = "default"
+
+// Limits and configs of server
+type Options struct {
+	// Configs which requests can choose by name, `DefaultConfig` is used by
+	// default and is embedded defaults if missing
+	Configs map[string]*config.Loaded
+	// Limit of request body in bytes
+	MaxBytes int64
+	// Limit of total size of files unpacked from archive in bytes
+	MaxUnpacked int64
+	// Limit of number of files unpacked from archive
+	MaxFiles int
+	// Limit of time spent on measuring single request
+	Timeout time.Duration
+}
+
+// Default limits
+var DefaultOptions = Options{
+	MaxBytes:    10 << 20,
+	MaxUnpacked: 100 << 20,
+	MaxFiles:    10000,
+	Timeout:     30 * time.Second,
+}
+
+// HTTP handler of all endpoints
+type Server struct {
+	opts    Options
+	metrics *metrics
+	mux     *http.ServeMux
+}
+
+// Creates server, zero limits of options are taken from `DefaultOptions`
+func New(opts Options) *Server {
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = DefaultOptions.MaxBytes
+	}
+	if opts.MaxUnpacked <= 0 {
+		opts.MaxUnpacked = DefaultOptions.MaxUnpacked
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = DefaultOptions.MaxFiles
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultOptions.Timeout
+	}
+	configs := map[string]*config.Loaded{DefaultConfig: {Config: config.Default()}}
+	for name, loaded := range opts.Configs {
+		configs[name] = loaded
+	}
+	opts.Configs = configs
+
+	s := &Server{opts: opts, metrics: newMetrics(), mux: http.NewServeMux()}
+	s.handle("POST /v1/analyze", s.analyze)
+	s.handle("GET /v1/configs", s.configs)
+	s.handle("POST /v1/configs/validate", s.validate)
+	s.handle("GET /healthz", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok\n") })
+	s.handle("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		s.metrics.write(w)
+	})
+	return s
+}
+
+func (s *Server) handle(pattern string, h http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, s.metrics.instrument(pattern, h))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) { s.mux.ServeHTTP(w, r) }
+
+// Error returned as json
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, apiError{err.Error()})
+}
+
+// Reads body within limit, writes error response if it fails
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.opts.MaxBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return nil, false
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	return data, true
+}
+
+// Handles `POST /v1/analyze?config=name&name=file.go&format=json`: measures
+// single go file or all go files of zip, tar or tar.gz archive
+func (s *Server) analyze(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	loaded, ok := s.opts.Configs[query.Get("config")]
+	if query.Get("config") == "" {
+		loaded, ok = s.opts.Configs[DefaultConfig], true
+	}
+	if !ok {
+		writeError(w, http.StatusBadRequest, errors.New("unknown config "+query.Get("config")))
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if _, ok := report.Formats[format]; !ok {
+		writeError(w, http.StatusBadRequest, errors.New("unknown format "+format))
+		return
+	}
+	data, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	dir, err := os.MkdirTemp("", "gokys-serve-")
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer os.RemoveAll(dir)
+	err = unpack(data, query.Get("name"), dir, s.opts.MaxUnpacked, s.opts.MaxFiles)
+	if errors.Is(err, errTooLarge) || errors.Is(err, errTooMany) {
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.opts.Timeout)
+	defer cancel()
+	result, err := measureDir(ctx, dir, loaded)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusServiceUnavailable, errors.New("analysis timed out"))
+		return
+	case err != nil:
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	s.metrics.analyzed(len(result), len(data))
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, report.New(result), format); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	w.Write(buf.Bytes())
+}
+
+// Measures all go files in directory, paths of results are relative to it
+func measureDir(ctx context.Context, dir string, loaded *config.Loaded) ([]report.File, error) {
+	files := []report.File{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".go" {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		// overlays match paths inside uploaded module
+		cfg := loaded.ForRelative(filepath.ToSlash(rel))
+		// embedded files are resolved inside of the file's directory, so
+		// uploads can not read files outside of `dir`
+		result, err := report.MeasureFileContext(ctx, path, &cfg)
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+			return err
+		} else if err != nil {
+			return errors.New(trimDir(dir, err.Error()))
+		}
+		result.Path = filepath.ToSlash(rel)
+		for i := range result.Assets {
+			if p, err := filepath.Rel(dir, result.Assets[i].Path); err == nil {
+				result.Assets[i].Path = filepath.ToSlash(p)
+			}
+		}
+		for i := range result.Docs.Undocumented {
+			result.Docs.Undocumented[i].Path = result.Path
+		}
+		files = append(files, result)
+		return nil
+	})
+	return files, err
+}
+
+// Removes temporary directory from messages
+func trimDir(dir, message string) string {
+	return strings.ReplaceAll(message, dir+string(filepath.Separator), "")
+}
+
+// Config available to requests
+type configInfo struct {
+	Name string `json:"name"`
+	// Path to config file, empty for embedded defaults
+	Path string `json:"path"`
+	// Number of overlays of config
+	Overlays int `json:"overlays"`
+}
+
+// Handles `GET /v1/configs`: lists configs by name
+func (s *Server) configs(w http.ResponseWriter, r *http.Request) {
+	list := []configInfo{}
+	for name, loaded := range s.opts.Configs {
+		list = append(list, configInfo{Name: name, Path: loaded.Path, Overlays: len(loaded.Overlays)})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	writeJSON(w, http.StatusOK, list)
+}
+
+// Problem of validated config
+type issue struct {
+	Key     string `json:"key"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// Result of config validation
+type validation struct {
+	Valid    bool    `json:"valid"`
+	Errors   []issue `json:"errors"`
+	Warnings []issue `json:"warnings"`
+}
+
+// Handles `POST /v1/configs/validate?name=.gokys.yaml`: validates config in
+// body, format is detected by name or by content
+func (s *Server) validate(w http.ResponseWriter, r *http.Request) {
+	data, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	result := validation{Valid: true, Errors: []issue{}, Warnings: []issue{}}
+	loaded, err := config.Parse(r.URL.Query().Get("name"), data)
+	var errs config.Errors
+	switch {
+	case errors.As(err, &errs):
+		result.Valid = false
+		result.Errors = toIssues(errs)
+	case err != nil:
+		result.Valid = false
+		result.Errors = []issue{{Message: err.Error()}}
+	default:
+		result.Warnings = toIssues(loaded.Warnings)
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func toIssues(issues []config.Issue) []issue {
+	result := make([]issue, len(issues))
+	for i, is := range issues {
+		result[i] = issue{Key: is.Key.Path, Line: is.Key.Line, Column: is.Key.Column, Message: is.Message}
+	}
+	return result
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bragov4ik/go-kys/pkg/config"
+	"github.com/bragov4ik/go-kys/pkg/report"
+)
+
+const source = `package a
+
+import _ "embed"
+
+//go:embed q.sql
+var q string
+
+// F returns sign of x
+func F(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+`
+
+func zipOf(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func do(s *Server, method, target string, body []byte) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(method, target, bytes.NewReader(body)))
+	return rec
+}
+
+func TestAnalyze(t *testing.T) {
+	strict, err := config.Parse("strict.yaml", []byte("cyclomatic:\n  if: 50\nembed:\n  sql:\n    line: 1\noverlay:\n  - pattern: \"*_test.go\"\n    cyclomatic:\n      if: 0\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Options{Configs: map[string]*config.Loaded{"strict": strict}, MaxBytes: 4096, MaxUnpacked: 2048})
+
+	module := zipOf(t, map[string]string{
+		"mod/go.mod":     "module a\n",
+		"mod/a.go":       source,
+		"mod/q.sql":      "SELECT 1;\n",
+		"mod/a_test.go":  strings.Replace(source, "func F", "func G", 1),
+		"mod/sub/b.go":   "package sub\n",
+		"mod/README.txt": "not go",
+	})
+	tests := []struct {
+		name   string
+		target string
+		body   []byte
+		status int
+		check  func(t *testing.T, r report.Report)
+	}{
+		{"Source", "/v1/analyze?name=a.go", []byte(strings.Replace(source, "//go:embed q.sql\n", "", 1)), 200, func(t *testing.T, r report.Report) {
+			if len(r.Packages) != 1 || r.Packages[0].Files[0].Path != "a.go" || r.Score <= 0 {
+				t.Errorf("report = %+v, want single file a.go", r)
+			}
+		}},
+		{"Zip", "/v1/analyze?config=strict", module, 200, func(t *testing.T, r report.Report) {
+			if len(r.Packages) != 2 || len(r.Packages[0].Files) != 2 || r.AssetScore <= 0 {
+				t.Fatalf("report = %+v, want packages mod and mod/sub with embedded file", r)
+			}
+			files := r.Packages[0].Files
+			if files[0].Path != "mod/a.go" || files[0].Assets[0].Path != "mod/q.sql" {
+				t.Errorf("paths = %v, %v, want relative to archive", files[0].Path, files[0].Assets[0].Path)
+			}
+			if a, test := files[0].Funcs[0].Score, files[1].Funcs[0].Score; a-test < 49 {
+				t.Errorf("scores of F = %v and G = %v, want if weighted by 50 only in a.go", a, test)
+			}
+		}},
+		{"UnknownConfig", "/v1/analyze?config=missing", []byte(source), 400, nil},
+		{"UnknownFormat", "/v1/analyze?format=pdf", []byte(source), 400, nil},
+		{"SyntaxError", "/v1/analyze", []byte("package a\nfunc {"), 422, nil},
+		{"TooLarge", "/v1/analyze", bytes.Repeat([]byte("/"), 5000), 413, nil},
+		{"UnpackedTooLarge", "/v1/analyze", zipOf(t, map[string]string{"a.go": strings.Repeat("/", 3000)}), 413, nil},
+		{"ZipSlip", "/v1/analyze", zipOf(t, map[string]string{"../a.go": source}), 400, nil},
+		{"EmbedTraversal", "/v1/analyze", []byte(strings.Replace(source, "q.sql", "../../../../../../../etc/passwd", 1)), 422, nil},
+		{"EmbedAbsolute", "/v1/analyze", []byte(strings.Replace(source, "q.sql", "/etc/passwd", 1)), 422, nil},
+		{"EmbedParent", "/v1/analyze", zipOf(t, map[string]string{"sub/a.go": strings.Replace(source, "q.sql", "../*", 1), "q.sql": "SELECT 1;\n"}), 422, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(s, http.MethodPost, tt.target, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %v, want %v, body %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.check != nil {
+				r, err := report.ReadJSON(rec.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tt.check(t, r)
+			}
+		})
+	}
+
+	if rec := do(s, http.MethodPost, "/v1/analyze", []byte("package a\nfunc {")); !strings.Contains(rec.Body.String(), `"main.go:2:`) {
+		t.Errorf("syntax error response = %s, want position in main.go without temporary directory", rec.Body)
+	}
+	traversal := strings.Replace(source, "q.sql", "../../../../../../../etc/passwd", 1)
+	if rec := do(s, http.MethodPost, "/v1/analyze", []byte(traversal)); !strings.Contains(rec.Body.String(), "invalid embed pattern") {
+		t.Errorf("traversal response = %s, want invalid embed pattern error", rec.Body)
+	}
+	if rec := do(s, http.MethodPost, "/v1/analyze?format=total", []byte(source)); rec.Code != 200 || rec.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Errorf("total format response = %v %v", rec.Code, rec.Header())
+	}
+	if rec := do(s, http.MethodGet, "/v1/analyze", nil); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /v1/analyze status = %v, want 405", rec.Code)
+	}
+}
+
+func TestAnalyzeTimeout(t *testing.T) {
+	s := New(Options{Timeout: time.Nanosecond})
+	if rec := do(s, http.MethodPost, "/v1/analyze", []byte(source)); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %v, want 503, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestConfigs(t *testing.T) {
+	strict, err := config.Parse("strict.yaml", []byte("cyclomatic:\n  if: 50\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := New(Options{Configs: map[string]*config.Loaded{"strict": strict}})
+
+	rec := do(s, http.MethodGet, "/v1/configs", nil)
+	var list []configInfo
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	want := []configInfo{{Name: "default"}, {Name: "strict", Path: "strict.yaml"}}
+	if len(list) != 2 || list[0] != want[0] || list[1] != want[1] {
+		t.Errorf("configs = %+v, want %+v", list, want)
+	}
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		valid    bool
+		errors   int
+		warnings bool
+	}{
+		{"Valid", "/v1/configs/validate?name=c.yaml", "cyclomatic:\n  if: 1\n", true, 0, true},
+		{"UnknownKey", "/v1/configs/validate?name=c.json", `{"cyclomatic": {"iff": 1}}`, false, 1, false},
+		{"DetectedXML", "/v1/configs/validate", "<config><halstead>-1</halstead></config>", false, 1, false},
+		{"Malformed", "/v1/configs/validate?name=c.toml", "[[[", false, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(s, http.MethodPost, tt.target, []byte(tt.body))
+			var got validation
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Valid != tt.valid || len(got.Errors) != tt.errors || (len(got.Warnings) > 0) != tt.warnings {
+				t.Errorf("validation = %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Returned when unpacked files exceed limits
+var (
+	errTooLarge = errors.New("unpacked files are too large")
+	errTooMany  = errors.New("archive has too many files")
+)
+
+// Kind of uploaded body
+type kind int
+
+const (
+	sourceFile kind = iota
+	zipArchive
+	tarArchive
+	tarGzArchive
+)
+
+// Detects kind of body by its magic bytes
+func detect(data []byte) kind {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) || bytes.HasPrefix(data, []byte("PK\x05\x06")):
+		return zipArchive
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		return tarGzArchive
+	case len(data) > 262 && string(data[257:262]) == "ustar":
+		return tarArchive
+	}
+	return sourceFile
+}
+
+// Writes files of archive (or single source named `name`) into directory `dir`,
+// at most `limit` bytes in at most `maxFiles` files are written
+func unpack(data []byte, name, dir string, limit int64, maxFiles int) error {
+	w := &writer{dir: dir, left: limit, files: maxFiles}
+	switch detect(data) {
+	case zipArchive:
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if !f.Mode().IsRegular() {
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			err = w.write(f.Name, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case tarGzArchive:
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return unpackTar(tar.NewReader(gz), w)
+	case tarArchive:
+		return unpackTar(tar.NewReader(bytes.NewReader(data)), w)
+	}
+	if name == "" {
+		name = "main.go"
+	}
+	return w.write(path.Base(name), bytes.NewReader(data))
+}
+
+func unpackTar(tr *tar.Reader, w *writer) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		// links are skipped, so archives can not point outside of directory
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := w.write(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// Writes files into directory keeping track of their total size and number
+type writer struct {
+	dir   string
+	left  int64
+	files int
+}
+
+func (w *writer) write(name string, r io.Reader) error {
+	if w.files--; w.files < 0 {
+		return errTooMany
+	}
+	clean := path.Clean("/" + strings.ReplaceAll(name, `\`, "/"))[1:]
+	if clean == "" || clean != strings.TrimPrefix(name, "./") {
+		return fmt.Errorf("invalid file name %q in archive", name)
+	}
+	target := filepath.Join(w.dir, filepath.FromSlash(clean))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(f, io.LimitReader(r, w.left+1))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if w.left -= n; w.left < 0 {
+		return errTooLarge
+	}
+	return nil
+}
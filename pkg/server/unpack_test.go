@@ -0,0 +1,100 @@
+package server
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tarOf(t *testing.T, headers []tar.Header, gz bool) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var zw *gzip.Writer
+	if gz {
+		zw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(zw)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	for _, h := range headers {
+		h := h
+		content := h.Name
+		if h.Typeflag == tar.TypeReg {
+			h.Size = int64(len(content))
+		}
+		h.Mode = 0o644
+		if err := tw.WriteHeader(&h); err != nil {
+			t.Fatal(err)
+		}
+		if h.Typeflag == tar.TypeReg {
+			tw.Write([]byte(content))
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if zw != nil {
+		zw.Close()
+	}
+	return buf.Bytes()
+}
+
+func TestUnpack(t *testing.T) {
+	files := []tar.Header{
+		{Name: "./m/a.go", Typeflag: tar.TypeReg},
+		{Name: "m/link", Typeflag: tar.TypeSymlink, Linkname: "/etc/passwd"},
+		{Name: "m/sub/", Typeflag: tar.TypeDir},
+		{Name: "m/sub/b.go", Typeflag: tar.TypeReg},
+	}
+	tests := []struct {
+		name  string
+		data  []byte
+		kind  kind
+		files []string
+		err   bool
+	}{
+		{"Source", []byte("package a\n"), sourceFile, []string{"x.go"}, false},
+		{"Tar", tarOf(t, files, false), tarArchive, []string{"m/a.go", "m/sub/b.go"}, false},
+		{"TarGz", tarOf(t, files, true), tarGzArchive, []string{"m/a.go", "m/sub/b.go"}, false},
+		{"Zip", zipOf(t, map[string]string{"a.go": "package a\n"}), zipArchive, []string{"a.go"}, false},
+		{"Absolute", tarOf(t, []tar.Header{{Name: "/tmp/a.go", Typeflag: tar.TypeReg}}, false), tarArchive, nil, true},
+		{"Parent", tarOf(t, []tar.Header{{Name: "a/../../a.go", Typeflag: tar.TypeReg}}, true), tarGzArchive, nil, true},
+		{"Duplicate", tarOf(t, []tar.Header{{Name: "a.go", Typeflag: tar.TypeReg}, {Name: "a.go", Typeflag: tar.TypeReg}}, false), tarArchive, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detect(tt.data); got != tt.kind {
+				t.Errorf("detect() = %v, want %v", got, tt.kind)
+			}
+			dir := t.TempDir()
+			err := unpack(tt.data, "../x.go", dir, 1<<20, 10)
+			if (err != nil) != tt.err {
+				t.Fatalf("unpack() error = %v, want error %v", err, tt.err)
+			}
+			for _, file := range tt.files {
+				if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+					t.Error(err)
+				}
+			}
+			if _, err := os.Lstat(filepath.Join(dir, "m", "link")); err == nil {
+				t.Error("symlink should not be unpacked")
+			}
+		})
+	}
+
+	if err := unpack([]byte("package a\n"), "a.go", t.TempDir(), 5, 10); err != errTooLarge {
+		t.Errorf("unpack() error = %v, want %v", err, errTooLarge)
+	}
+	// empty files do not count towards size limit
+	var empty []tar.Header
+	for _, name := range []string{"a", "b", "c"} {
+		empty = append(empty, tar.Header{Name: name, Typeflag: tar.TypeReg})
+	}
+	if err := unpack(tarOf(t, empty, false), "", t.TempDir(), 1<<20, 2); err != errTooMany {
+		t.Errorf("unpack() error = %v, want %v", err, errTooMany)
+	}
+}
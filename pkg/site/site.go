@@ -0,0 +1,149 @@
+// Package site writes report as static html site with per-line heatmaps of
+// measured files.
+package site
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bragov4ik/go-kys/pkg/annotate"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+//go:embed templates
+var files embed.FS
+
+var templates = template.Must(template.New("").Funcs(template.FuncMap{
+	"minutes": func(m float64) string { return fmt.Sprintf("%.2f", m) },
+}).ParseFS(files, "templates/*.html"))
+
+// Data of index page
+type index struct {
+	Report report.Report
+	// Minutes of metrics over all files
+	Parts []wmfp.Part
+	// Pages of files by their paths
+	Pages map[string]string
+}
+
+// Data of file page
+type filePage struct {
+	File  report.File
+	Parts []wmfp.Part
+	Lines []line
+}
+
+// Line of file page
+type line struct {
+	annotate.Line
+	// Opacity of shading, minutes relative to hottest line of file
+	Shade float64
+	// Metrics and weights which produced minutes of line
+	Title string
+}
+
+// Writes site with `index.html` and a page for every file of report into
+// directory `dir`. Files are measured again to attribute their scores to
+// lines, so `cfg` should return configs used for report
+func Write(dir string, r report.Report, cfg func(path string) wmfp.Config) error {
+	if err := os.MkdirAll(filepath.Join(dir, "files"), 0o755); err != nil {
+		return err
+	}
+	style, err := fs.ReadFile(files, "templates/style.css")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "style.css"), style, 0o644); err != nil {
+		return err
+	}
+
+	data := index{Report: r, Pages: pageNames(r)}
+	var total wmfp.Breakdown
+	for _, pkg := range r.Packages {
+		for _, file := range pkg.Files {
+			config := cfg(file.Path)
+			breakdown := file.Counts.Breakdown(&config)
+			total = total.Add(breakdown)
+			page, err := newFilePage(file, breakdown.Parts(), &config)
+			if err != nil {
+				return err
+			}
+			if err := execute(filepath.Join(dir, data.Pages[file.Path]), "file.html", page); err != nil {
+				return err
+			}
+		}
+	}
+	data.Parts = total.Parts()
+	return execute(filepath.Join(dir, "index.html"), "index.html", data)
+}
+
+func newFilePage(file report.File, parts []wmfp.Part, cfg *wmfp.Config) (filePage, error) {
+	annotated, err := annotate.AnnotateFile(file.Path, cfg)
+	if err != nil {
+		return filePage{}, err
+	}
+	page := filePage{File: file, Parts: parts}
+	for _, l := range annotated.Lines {
+		result := line{Line: l}
+		if annotated.Max > 0 {
+			result.Shade = l.Minutes / annotated.Max
+		}
+		result.Title = describe(&l.Counts, cfg)
+		page.Lines = append(page.Lines, result)
+	}
+	return page, nil
+}
+
+// Returns lines like `cyclomatic.if: 1 × 2 = 2.00 min` for counts which add
+// minutes, largest first
+func describe(counts *wmfp.Counts, cfg *wmfp.Config) string {
+	terms := counts.Terms(cfg)
+	sort.SliceStable(terms, func(i, j int) bool { return terms[i].Minutes > terms[j].Minutes })
+	var lines []string
+	for _, term := range terms {
+		if term.Minutes == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %.4g × %.4g = %.2f min",
+			term.Name, term.Count, term.Minutes/term.Count, term.Minutes))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Returns names of pages of all files relative to site directory
+func pageNames(r report.Report) map[string]string {
+	names := make(map[string]string)
+	taken := make(map[string]bool)
+	replacer := strings.NewReplacer("/", "_", `\`, "_", ":", "_")
+	for _, pkg := range r.Packages {
+		for _, file := range pkg.Files {
+			base := strings.TrimPrefix(replacer.Replace(filepath.ToSlash(filepath.Clean(file.Path))), "_")
+			name := base + ".html"
+			for i := 2; taken[name]; i++ {
+				name = fmt.Sprintf("%s-%d.html", base, i)
+			}
+			taken[name] = true
+			names[file.Path] = "files/" + name
+		}
+	}
+	return names
+}
+
+func execute(path, name string, data interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = templates.ExecuteTemplate(f, name, data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
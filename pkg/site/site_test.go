@@ -0,0 +1,86 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/report"
+	"github.com/bragov4ik/go-kys/pkg/wmfp"
+)
+
+const src = `package p
+
+// Returns sign of x
+func sign(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+`
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "src", "p.go")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := wmfp.Config{CycloComp: cyclo.Weights{If: 2}, Halstead: 0.1}
+	file, err := report.MeasureFile(path, &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := report.New([]report.File{file})
+
+	out := filepath.Join(dir, "site")
+	if err := Write(out, r, func(string) wmfp.Config { return cfg }); err != nil {
+		t.Fatal(err)
+	}
+	page := pageNames(r)[path]
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"index.html", []string{`href="` + page + `"`, "<td>cyclomatic</td><td>3.00</td>", filepath.Dir(path)}},
+		{page, []string{
+			`<a href="#L4">sign</a>`,
+			`id="L5" title="cyclomatic.if: 1 × 2 = 2.00 min`,
+			"background-color: rgba(255, 69, 0, 1.000)",
+			"if x &gt; 0 {",
+		}},
+		{"style.css", []string{"table.source"}},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join(out, tt.name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("%s should contain %q", tt.name, want)
+			}
+		}
+	}
+}
+
+func TestPageNames(t *testing.T) {
+	r := report.New([]report.File{{Path: "a/b_c.go"}, {Path: "a_b/c.go"}, {Path: "/abs/d.go"}})
+	want := map[string]string{
+		"a/b_c.go":  "files/a_b_c.go.html",
+		"a_b/c.go":  "files/a_b_c.go-2.html",
+		"/abs/d.go": "files/abs_d.go.html",
+	}
+	got := pageNames(r)
+	for path, name := range want {
+		if got[path] != name {
+			t.Errorf("page of %s = %q, want %q", path, got[path], name)
+		}
+	}
+}
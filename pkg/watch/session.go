@@ -0,0 +1,184 @@
+// Package watch re-measures go files when they change and summarizes how the
+// score moved since the start of a session.
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bragov4ik/go-kys/pkg/report"
+)
+
+// Measures single file, e.g. `report.MeasureFile` with config of the file
+type MeasureFunc func(path string) (report.File, error)
+
+// Measured files since start of watching
+type Session struct {
+	measure MeasureFunc
+	start   map[string]report.File
+	files   map[string]report.File
+}
+
+// Change of function score since start of session
+type FuncDelta struct {
+	// Path to file of function
+	Path string `json:"path"`
+	// Name of function, methods are prefixed with receiver type
+	Name string `json:"name"`
+	// Score at start of session, 0 for new functions
+	Before float64 `json:"before"`
+	// Current score, 0 for removed functions
+	After float64 `json:"after"`
+	// Difference between current and starting score
+	Delta float64 `json:"delta"`
+}
+
+// State of session after an update
+type Summary struct {
+	// Time of update
+	Time time.Time `json:"time"`
+	// Files measured again by update
+	Changed []string `json:"changed"`
+	// Total score at start of session
+	Start float64 `json:"start"`
+	// Current total score
+	Total float64 `json:"total"`
+	// Difference between current and starting score
+	Delta float64 `json:"delta"`
+	// Functions with largest changes since start
+	Funcs []FuncDelta `json:"funcs"`
+}
+
+// Measures all files, their scores are the start of session
+func NewSession(files []string, measure MeasureFunc) (*Session, error) {
+	s := &Session{measure: measure, start: make(map[string]report.File), files: make(map[string]report.File)}
+	for _, path := range files {
+		result, err := measure(path)
+		if err != nil {
+			return nil, err
+		}
+		s.start[path] = result
+		s.files[path] = result
+	}
+	return s, nil
+}
+
+// Measures changed files again, files which do not exist anymore are removed
+// from session. Files which fail to measure (e.g. are being edited) keep their
+// previous results, their errors are returned after all files are updated
+func (s *Session) Update(changed []string) error {
+	var errs []error
+	for _, path := range changed {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(s.files, path)
+			continue
+		}
+		result, err := s.measure(path)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		s.files[path] = result
+	}
+	if len(errs) == 1 {
+		return errs[0]
+	} else if len(errs) > 1 {
+		return fmt.Errorf("%w (and %d more errors)", errs[0], len(errs)-1)
+	}
+	return nil
+}
+
+// Returns current report of all files
+func (s *Session) Report() report.Report {
+	files := make([]report.File, 0, len(s.files))
+	for _, file := range s.files {
+		files = append(files, file)
+	}
+	return report.New(files)
+}
+
+// Summarizes session with at most `top` functions with largest changes
+func (s *Session) Summary(changed []string, top int) Summary {
+	summary := Summary{Time: time.Now(), Changed: changed, Funcs: []FuncDelta{}}
+	if summary.Changed == nil {
+		summary.Changed = []string{}
+	}
+	for _, file := range s.start {
+		summary.Start += file.Score
+	}
+	for _, file := range s.files {
+		summary.Total += file.Score
+	}
+	summary.Delta = summary.Total - summary.Start
+
+	before := funcScores(s.start)
+	after := funcScores(s.files)
+	for key, score := range after {
+		before[key] -= score
+	}
+	for key, diff := range before {
+		if diff == 0 {
+			continue
+		}
+		summary.Funcs = append(summary.Funcs, FuncDelta{
+			Path:   key.path,
+			Name:   key.name,
+			Before: after[key] + diff,
+			After:  after[key],
+			Delta:  -diff,
+		})
+	}
+	sort.Slice(summary.Funcs, func(i, j int) bool {
+		a, b := summary.Funcs[i], summary.Funcs[j]
+		if math.Abs(a.Delta) != math.Abs(b.Delta) {
+			return math.Abs(a.Delta) > math.Abs(b.Delta)
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Name < b.Name
+	})
+	if len(summary.Funcs) > top {
+		summary.Funcs = summary.Funcs[:top]
+	}
+	return summary
+}
+
+type funcKey struct{ path, name string }
+
+// Returns function scores by file and name, functions with the same name
+// (e.g. `init`) are summed
+func funcScores(files map[string]report.File) map[funcKey]float64 {
+	scores := make(map[funcKey]float64)
+	for path, file := range files {
+		for _, fn := range file.Funcs {
+			scores[funcKey{path, fn.Name}] += fn.Score
+		}
+	}
+	return scores
+}
+
+// Writes summary as a table
+func WriteSummary(w io.Writer, s Summary) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\ttotal %.2f\tdelta %+.2f\tstart %.2f\t\n", s.Time.Format("15:04:05"), s.Total, s.Delta, s.Start)
+	if len(s.Funcs) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "FUNC\tBEFORE\tAFTER\tDELTA\t")
+		for _, fn := range s.Funcs {
+			fmt.Fprintf(tw, "%s %s\t%.2f\t%.2f\t%+.2f\t\n", fn.Path, fn.Name, fn.Before, fn.After, fn.Delta)
+		}
+	}
+	return tw.Flush()
+}
+
+// Appends summary to session log as a single json line
+func WriteLog(w io.Writer, s Summary) error {
+	return json.NewEncoder(w).Encode(s)
+}
@@ -0,0 +1,94 @@
+package watch
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bragov4ik/go-kys/pkg/report"
+)
+
+func TestSession(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// scores of functions in files, measured by fake measurer
+	scores := map[string][]report.Func{
+		a: {{Name: "f", Score: 1}, {Name: "g", Score: 2}},
+		b: {{Name: "h", Score: 3}},
+	}
+	measure := func(path string) (report.File, error) {
+		funcs, ok := scores[path]
+		if !ok {
+			return report.File{}, errors.New("syntax error")
+		}
+		file := report.File{Path: path, Funcs: funcs}
+		for _, fn := range funcs {
+			file.Score += fn.Score
+		}
+		return file, nil
+	}
+
+	s, err := NewSession([]string{a, b}, measure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Summary(nil, 5); got.Start != 6 || got.Total != 6 || len(got.Funcs) != 0 {
+		t.Errorf("Summary() at start = %+v", got)
+	}
+
+	scores[a] = []report.Func{{Name: "f", Score: 4}, {Name: "k", Score: 0.5}}
+	if err := os.Remove(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update([]string{a, b}); err != nil {
+		t.Fatal(err)
+	}
+	got := s.Summary([]string{a, b}, 3)
+	want := []FuncDelta{
+		{Path: a, Name: "f", Before: 1, After: 4, Delta: 3},
+		{Path: b, Name: "h", Before: 3, After: 0, Delta: -3},
+		{Path: a, Name: "g", Before: 2, After: 0, Delta: -2},
+	}
+	if got.Start != 6 || got.Total != 4.5 || got.Delta != -1.5 || !reflect.DeepEqual(got.Funcs, want) {
+		t.Errorf("Summary() = %+v, want funcs %+v", got, want)
+	}
+	if r := s.Report(); len(r.Packages) != 1 || len(r.Packages[0].Files) != 1 {
+		t.Errorf("Report() = %+v, should contain only a.go", r)
+	}
+
+	delete(scores, a)
+	if err := s.Update([]string{a}); err == nil {
+		t.Error("Update() should fail when file fails to measure")
+	}
+	if got := s.Summary(nil, 3); got.Total != 4.5 {
+		t.Errorf("Summary().Total = %v after failed update, want previous 4.5", got.Total)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"total 4.50", "delta -1.50", "a.go f", "+3.00"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteSummary() = %q, should contain %q", buf.String(), want)
+		}
+	}
+	buf.Reset()
+	if err := WriteLog(&buf, got); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"delta":-1.5`) || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("WriteLog() = %q, should be a single json line", buf.String())
+	}
+}
@@ -0,0 +1,182 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Time to wait for more events before reporting changes, editors often write
+// a file in several steps
+const Debounce = 100 * time.Millisecond
+
+// Watches go files in directory trees of `roots` and calls `changed` with
+// changed, created and removed files until context is done. Directories are
+// watched with inotify (or analogues of other systems), or polled every `poll`
+// interval if it is not zero
+func Watch(ctx context.Context, roots []string, poll time.Duration, changed func([]string)) error {
+	if poll > 0 {
+		return watchPoll(ctx, roots, poll, changed)
+	}
+	return watchNotify(ctx, roots, changed)
+}
+
+// Returns go files in directory trees of `roots`
+func Files(roots []string) ([]string, error) {
+	var files []string
+	err := walk(roots, func(path string, d fs.DirEntry) error {
+		if isGo(path, d) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+func watchNotify(ctx context.Context, roots []string, changed func([]string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	// fsnotify is not recursive, every directory is watched separately
+	err = walk(roots, func(path string, d fs.DirEntry) error {
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(Debounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-watcher.Errors:
+			return err
+		case event := <-watcher.Events:
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() && event.Op&fsnotify.Create != 0 {
+				if err := watcher.Add(event.Name); err != nil {
+					return err
+				}
+				// files could be created before directory was watched
+				files, _ := Files([]string{event.Name})
+				for _, file := range files {
+					pending[file] = true
+				}
+			} else if filepath.Ext(event.Name) == ".go" && event.Op != fsnotify.Chmod {
+				pending[event.Name] = true
+			}
+			if len(pending) > 0 {
+				timer.Reset(Debounce)
+			}
+		case <-timer.C:
+			changed(sorted(pending))
+			pending = make(map[string]bool)
+		}
+	}
+}
+
+// Size and modification time of a file
+type stamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func watchPoll(ctx context.Context, roots []string, poll time.Duration, changed func([]string)) error {
+	prev, err := stamps(roots)
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		next, err := stamps(roots)
+		if err != nil {
+			return err
+		}
+		diff := make(map[string]bool)
+		for path, s := range next {
+			if old, ok := prev[path]; !ok || old != s {
+				diff[path] = true
+			}
+		}
+		for path := range prev {
+			if _, ok := next[path]; !ok {
+				diff[path] = true
+			}
+		}
+		if len(diff) > 0 {
+			changed(sorted(diff))
+		}
+		prev = next
+	}
+}
+
+func stamps(roots []string) (map[string]stamp, error) {
+	result := make(map[string]stamp)
+	err := walk(roots, func(path string, d fs.DirEntry) error {
+		if !isGo(path, d) {
+			return nil
+		}
+		info, err := d.Info()
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		result[path] = stamp{info.Size(), info.ModTime()}
+		return nil
+	})
+	return result, err
+}
+
+// Walks directory trees skipping hidden directories such as `.git`
+func walk(roots []string, fn func(string, fs.DirEntry) error) error {
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if os.IsNotExist(err) && path != root {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if d.IsDir() && path != root && len(d.Name()) > 1 && d.Name()[0] == '.' {
+				return filepath.SkipDir
+			}
+			return fn(path, d)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isGo(path string, d fs.DirEntry) bool {
+	return !d.IsDir() && filepath.Ext(path) == ".go"
+}
+
+func sorted(set map[string]bool) []string {
+	result := make([]string, 0, len(set))
+	for path := range set {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,99 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	tests := []struct {
+		name string
+		poll time.Duration
+	}{
+		{"Notify", 0},
+		{"Poll", 10 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			a := filepath.Join(dir, "a.go")
+			if err := os.WriteFile(a, []byte("package a\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(filepath.Join(dir, ".git"), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			changes := make(chan []string, 10)
+			done := make(chan error)
+			go func() { done <- Watch(ctx, []string{dir}, tt.poll, func(files []string) { changes <- files }) }()
+			// give watcher time to start before changing files
+			time.Sleep(50 * time.Millisecond)
+
+			sub := filepath.Join(dir, "sub")
+			if err := os.Mkdir(sub, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			b := filepath.Join(sub, "b.go")
+			writes := map[string]string{
+				a:                                  "package a\n\nfunc f() {}\n",
+				b:                                  "package sub\n",
+				filepath.Join(dir, "notes.txt"):    "not go",
+				filepath.Join(dir, ".git", "x.go"): "package x\n",
+			}
+			for path, data := range writes {
+				if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			seen := make(map[string]bool)
+			timeout := time.After(5 * time.Second)
+			for len(seen) < 2 {
+				select {
+				case files := <-changes:
+					for _, file := range files {
+						seen[file] = true
+					}
+				case <-timeout:
+					t.Fatalf("changed files = %v, want a.go and sub/b.go", seen)
+				}
+			}
+			if want := map[string]bool{a: true, b: true}; !reflect.DeepEqual(seen, want) {
+				t.Errorf("changed files = %v, want %v", seen, want)
+			}
+
+			cancel()
+			if err := <-done; err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.txt", "sub/c.go", ".hidden/d.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := Files([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "a.go"), filepath.Join(dir, "sub", "c.go")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+}
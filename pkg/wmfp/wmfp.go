@@ -4,13 +4,20 @@ package wmfp
 
 import (
 	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/bragov4ik/go-kys/pkg/arithmetic"
+	"github.com/bragov4ik/go-kys/pkg/assets"
 	codestruct "github.com/bragov4ik/go-kys/pkg/codestruct"
 	comments "github.com/bragov4ik/go-kys/pkg/comments"
 	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
+	"github.com/bragov4ik/go-kys/pkg/doccov"
 	halstead "github.com/bragov4ik/go-kys/pkg/halstead"
 	inline "github.com/bragov4ik/go-kys/pkg/inline"
+	"github.com/bragov4ik/go-kys/pkg/nesting"
+	"github.com/bragov4ik/go-kys/pkg/pragma"
 )
 
 // State for WMFP metrics
@@ -27,8 +34,45 @@ type MeasurerWMFP struct {
 	InlineData *inline.Metric
 	// State of complexity of arithmetic expressions
 	ArithmeticComp *arithmetic.Metric
+	// State of complexity of nested blocks
+	Nesting *nesting.Metric
+	// State of documentation coverage of exported identifiers
+	DocCov *doccov.Metric
+	// Directives which suppress or discount parts of code, nil if there are none
+	Directives *pragma.Directives
+	// Collect counts added by every node, see `Contributions`
+	Attribute bool
 
-	halstWeight float64
+	config Config
+	// counts of nodes parsed with directives or `Attribute` multiplied by their
+	// weights, metrics do not collect them
+	weighted Counts
+	// counts removed by directives
+	suppressed Counts
+	// operators and operands removed by directives, halstead is not additive,
+	// so volume of the whole code is split between them
+	suppressedTokens float64
+	// counts added by every node when `Attribute` is set
+	contributions []contribution
+}
+
+// Counts added by a single node itself, counts of its children are added by
+// children
+type Contribution struct {
+	// Node which added counts
+	Node ast.Node
+	// Counts added by node multiplied by weight of its directives. Halstead
+	// volume is split evenly between all operators and operands
+	Counts Counts
+}
+
+type contribution struct {
+	node   ast.Node
+	counts Counts
+	// directive weight of node
+	weight float64
+	// operators and operands added by node
+	tokens uint
 }
 
 // Interface for underlaying metrics
@@ -42,17 +86,227 @@ type Metric interface {
 // Config with all weights for underlaying metrics
 type Config struct {
 	// Cyclo complexity weights
-	CycloComp cyclo.Weights `xml:"cyclomatic"`
+	CycloComp cyclo.Weights `xml:"cyclomatic" yaml:"cyclomatic" json:"cyclomatic" toml:"cyclomatic"`
 	// Comments complexity weights
-	Comment comments.Weights `xml:"comment"`
+	Comment comments.Weights `xml:"comment" yaml:"comment" json:"comment" toml:"comment"`
 	// Code structure complexity weights
-	CodeStructComp codestruct.Weights `xml:"codestruct"`
+	CodeStructComp codestruct.Weights `xml:"codestruct" yaml:"codestruct" json:"codestruct" toml:"codestruct"`
 	// Inline data complexity weights
-	InlineData inline.Weights `xml:"inline"`
+	InlineData inline.Weights `xml:"inline" yaml:"inline" json:"inline" toml:"inline"`
 	// Arithmetic expression complexity weights
-	ArithmeticComp arithmetic.Weights `xml:"arithmetic"`
+	ArithmeticComp arithmetic.Weights `xml:"arithmetic" yaml:"arithmetic" json:"arithmetic" toml:"arithmetic"`
 	// Halstead metric weight
-	Halstead float64 `xml:"halstead"`
+	Halstead float64 `xml:"halstead" yaml:"halstead" json:"halstead" toml:"halstead"`
+	// Nesting depth weights
+	Nesting nesting.Weights `xml:"nesting" yaml:"nesting" json:"nesting" toml:"nesting"`
+	// Weight of missing documentation of exported identifiers
+	DocCov doccov.Weights `xml:"doccov" yaml:"doccov" json:"doccov" toml:"doccov"`
+	// Weights of files embedded with `//go:embed`, they are measured
+	// separately as they are not part of ast
+	Assets assets.Weights `xml:"embed" yaml:"embed" json:"embed" toml:"embed"`
+}
+
+// Raw counts of all metrics, one scan can be scored with any config. Counts
+// of nodes with directives are multiplied by their weights
+type Counts struct {
+	// Cyclo complexity counts
+	CycloComp cyclo.Counts `json:"cyclomatic"`
+	// Comments counts
+	Comment comments.Counts `json:"comment"`
+	// Code structure counts
+	CodeStructComp codestruct.Counts `json:"codestruct"`
+	// Inline data counts
+	InlineData inline.Counts `json:"inline"`
+	// Arithmetic operation counts
+	ArithmeticComp arithmetic.Counts `json:"arithmetic"`
+	// Halstead volume
+	Halstead float64 `json:"halstead"`
+	// Nesting depth counts
+	Nesting nesting.Counts `json:"nesting"`
+	// Undocumented identifier counts
+	DocCov doccov.Counts `json:"doccov"`
+	// Sizes of embedded files, they are not counted by measurer
+	Assets assets.Counts `json:"embed"`
+}
+
+// Minutes of every metric
+type Breakdown struct {
+	// Cyclo complexity minutes
+	CycloComp float64 `json:"cyclomatic"`
+	// Comments minutes
+	Comment float64 `json:"comment"`
+	// Code structure minutes
+	CodeStructComp float64 `json:"codestruct"`
+	// Inline data minutes
+	InlineData float64 `json:"inline"`
+	// Arithmetic expression minutes
+	ArithmeticComp float64 `json:"arithmetic"`
+	// Halstead volume minutes
+	Halstead float64 `json:"halstead"`
+	// Nesting depth minutes
+	Nesting float64 `json:"nesting"`
+	// Missing documentation minutes
+	DocCov float64 `json:"doccov"`
+	// Embedded files minutes
+	Assets float64 `json:"embed"`
+}
+
+// Minutes of a single metric
+type Part struct {
+	// Name of metric, the same as in config
+	Name string
+	// Minutes of metric
+	Minutes float64
+}
+
+// Returns minutes of metrics in order of fields
+func (b *Breakdown) Parts() []Part {
+	v := reflect.ValueOf(b).Elem()
+	parts := make([]Part, v.NumField())
+	for i := range parts {
+		parts[i] = Part{Name: v.Type().Field(i).Tag.Get("json"), Minutes: v.Field(i).Float()}
+	}
+	return parts
+}
+
+// Returns sum of all metrics
+func (b *Breakdown) Total() (total float64) {
+	for _, part := range b.Parts() {
+		total += part.Minutes
+	}
+	return
+}
+
+// Computes minutes of every metric from counts
+func (c *Counts) Breakdown(cfg *Config) Breakdown {
+	return Breakdown{
+		CycloComp:      c.CycloComp.Score(&cfg.CycloComp),
+		Comment:        c.Comment.Score(&cfg.Comment),
+		CodeStructComp: c.CodeStructComp.Score(&cfg.CodeStructComp),
+		InlineData:     c.InlineData.Score(&cfg.InlineData),
+		ArithmeticComp: c.ArithmeticComp.Score(&cfg.ArithmeticComp),
+		Halstead:       c.Halstead * cfg.Halstead,
+		Nesting:        c.Nesting.Score(&cfg.Nesting),
+		DocCov:         c.DocCov.Score(&cfg.DocCov),
+		Assets:         c.Assets.Score(&cfg.Assets),
+	}
+}
+
+// Computes score in minutes from counts
+func (c *Counts) Score(cfg *Config) float64 {
+	b := c.Breakdown(cfg)
+	return b.Total()
+}
+
+// Single count together with minutes it adds to score
+type Term struct {
+	// Dot separated path of count, e.g. `cyclomatic.if`, elements of lists
+	// are numbered, e.g. `nesting.depth.3`
+	Name string
+	// Value of count
+	Count float64
+	// Minutes added by count, `Minutes / Count` is its effective weight
+	Minutes float64
+}
+
+// Returns terms of all non zero counts in order of fields
+func (c *Counts) Terms(cfg *Config) []Term {
+	var terms []Term
+	forEachCount(reflect.ValueOf(c).Elem(), "", func(name string, path []int, value float64) {
+		var single Counts
+		countAt(reflect.ValueOf(&single).Elem(), path).SetFloat(value)
+		terms = append(terms, Term{Name: name, Count: value, Minutes: single.Score(cfg)})
+	})
+	return terms
+}
+
+// Calls `fn` for every non zero float field or element of float slice with
+// its json path and index, see `countAt`
+func forEachCount(v reflect.Value, prefix string, fn func(name string, path []int, value float64), index ...int) {
+	for i := 0; i < v.NumField(); i++ {
+		name := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		path := append(append([]int{}, index...), i)
+		switch field := v.Field(i); field.Kind() {
+		case reflect.Float64:
+			if field.Float() != 0 {
+				fn(name, path, field.Float())
+			}
+		case reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				if value := field.Index(j).Float(); value != 0 {
+					fn(name+"."+strconv.Itoa(j), append(path[:len(path):len(path)], j), value)
+				}
+			}
+		case reflect.Struct:
+			forEachCount(field, name, fn, path...)
+		}
+	}
+}
+
+// Returns settable count at index of `forEachCount`, slices are grown to fit it
+func countAt(v reflect.Value, path []int) reflect.Value {
+	for _, i := range path {
+		if v.Kind() != reflect.Slice {
+			v = v.Field(i)
+			continue
+		}
+		if v.Len() <= i {
+			grown := reflect.MakeSlice(v.Type(), i+1, i+1)
+			reflect.Copy(grown, v)
+			v.Set(grown)
+		}
+		v = v.Index(i)
+	}
+	return v
+}
+
+// Returns sum of breakdowns
+func (b Breakdown) Add(other Breakdown) Breakdown {
+	addScaled(reflect.ValueOf(&b).Elem(), reflect.ValueOf(other), 1)
+	return b
+}
+
+// Returns sum of counts
+func (c Counts) Add(other Counts) Counts {
+	addScaled(reflect.ValueOf(&c).Elem(), reflect.ValueOf(other), 1)
+	return c
+}
+
+// Returns counts multiplied by weight
+func (c Counts) Scale(weight float64) Counts {
+	var scaled Counts
+	addScaled(reflect.ValueOf(&scaled).Elem(), reflect.ValueOf(c), weight)
+	return scaled
+}
+
+// Adds all float fields of `src` multiplied by `scale` to `dst`, slices are
+// added elementwise into a new slice, so `dst` never shares them with `src`
+func addScaled(dst, src reflect.Value, scale float64) {
+	switch dst.Kind() {
+	case reflect.Float64:
+		dst.SetFloat(dst.Float() + src.Float()*scale)
+	case reflect.Slice:
+		if src.Len() == 0 {
+			return
+		}
+		n := dst.Len()
+		if src.Len() > n {
+			n = src.Len()
+		}
+		sum := reflect.MakeSlice(dst.Type(), n, n)
+		reflect.Copy(sum, dst)
+		for i := 0; i < src.Len(); i++ {
+			addScaled(sum.Index(i), src.Index(i), scale)
+		}
+		dst.Set(sum)
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			addScaled(dst.Field(i), src.Field(i), scale)
+		}
+	}
 }
 
 // Constructor for WMFP metric
@@ -75,42 +329,152 @@ func NewMeasurerWMFP(config *Config) MeasurerWMFP {
 		ArithmeticComp: &arithmetic.Metric{
 			Config: config.ArithmeticComp,
 		},
-		halstWeight: config.Halstead,
+		Nesting: &nesting.Metric{
+			Config: config.Nesting,
+		},
+		DocCov: &doccov.Metric{
+			Config: config.DocCov,
+		},
+		config: *config,
 	}
 }
 
 // Parses single file using WMFP metric
-func (m *MeasurerWMFP) ParseFile(file *ast.File) {
-	ast.Inspect(file, func(n ast.Node) bool {
-		m.parseNode(n)
+func (m *MeasurerWMFP) ParseFile(file *ast.File) { m.Parse(file) }
+
+// Parses ast subtree (e.g. single declaration) using WMFP metric, nodes with
+// directives and their children are weighted
+func (m *MeasurerWMFP) Parse(node ast.Node) {
+	weights := []float64{m.Directives.File()}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			weights = weights[:len(weights)-1]
+			return true
+		}
+		weight := weights[len(weights)-1]
+		if w, ok := m.Directives.Weight(n); ok {
+			weight *= w
+		}
+		weights = append(weights, weight)
+		m.parseNode(n, weight)
 		return true
 	})
 }
 
-// Returns final score of metric
-func (m *MeasurerWMFP) Finish() (total float64) {
-	total += m.Comments.Finish()
-	total += m.Cyclo.Finish()
-	total += m.Halst.Finish() * m.halstWeight
-	total += m.Codestruct.Finish()
-	total += m.InlineData.Finish()
-	total += m.ArithmeticComp.Finish()
-	return
+// Returns final score of metric without effort suppressed by directives
+func (m *MeasurerWMFP) Finish() float64 {
+	counts := m.Counts()
+	return counts.Score(&m.config)
+}
+
+// Returns effort which was suppressed or discounted by directives
+func (m *MeasurerWMFP) Suppressed() float64 {
+	counts := m.SuppressedCounts()
+	return counts.Score(&m.config)
+}
+
+// Returns counts of all metrics without counts suppressed by directives
+func (m *MeasurerWMFP) Counts() Counts {
+	counts := m.rawCounts().Add(m.weighted)
+	counts.Halstead = m.Halst.Finish() - m.suppressedHalstead()
+	return counts
+}
+
+// Returns counts which were suppressed or discounted by directives
+func (m *MeasurerWMFP) SuppressedCounts() Counts {
+	counts := m.suppressed
+	counts.Halstead = m.suppressedHalstead()
+	return counts
 }
 
+// Returns share of halstead volume of operators and operands removed by directives
+func (m *MeasurerWMFP) suppressedHalstead() float64 {
+	if m.suppressedTokens == 0 {
+		return 0
+	}
+	return m.Halst.Finish() * m.suppressedTokens / float64(m.Halst.Length())
+}
+
+// Returns counts added by every node in order of parsing, sum of their counts
+// equals `Counts`. Contributions are collected only if `Attribute` was set
+// before parsing
+func (m *MeasurerWMFP) Contributions() []Contribution {
+	// halstead volume per operator or operand
+	var perToken float64
+	if length := m.Halst.Length(); length > 0 {
+		perToken = m.Halst.Finish() / float64(length)
+	}
+	result := make([]Contribution, len(m.contributions))
+	for i, c := range m.contributions {
+		result[i] = Contribution{Node: c.node, Counts: c.counts}
+		result[i].Counts.Halstead = float64(c.tokens) * perToken * c.weight
+	}
+	return result
+}
+
+// Returns counts of additive metrics collected by metrics themselves
+func (m *MeasurerWMFP) rawCounts() Counts {
+	return Counts{
+		CycloComp:      m.Cyclo.Counts(),
+		Comment:        m.Comments.Counts(),
+		CodeStructComp: m.Codestruct.Counts(),
+		InlineData:     m.InlineData.Counts(),
+		ArithmeticComp: m.ArithmeticComp.Counts(),
+		Nesting:        m.Nesting.Counts(),
+		DocCov:         m.DocCov.Counts(),
+	}
+}
+
+// Returns additive metrics, halstead is handled separately
 func (m *MeasurerWMFP) metrics() []Metric {
 	return []Metric{
 		m.Comments,
 		m.Cyclo,
-		m.Halst,
 		m.Codestruct,
 		m.InlineData,
 		m.ArithmeticComp,
+		m.Nesting,
+		m.DocCov,
+	}
+}
+
+// Returns counts added by node, metrics do not collect them
+func (m *MeasurerWMFP) count(n ast.Node) Counts {
+	return Counts{
+		CycloComp:      m.Cyclo.Count(n),
+		Comment:        m.Comments.Count(n),
+		CodeStructComp: m.Codestruct.Count(n),
+		InlineData:     m.InlineData.Count(n),
+		ArithmeticComp: m.ArithmeticComp.Count(n),
+		Nesting:        m.Nesting.Count(n),
+		DocCov:         m.DocCov.Count(n),
 	}
 }
 
-func (measurer *MeasurerWMFP) parseNode(n ast.Node) {
-	for _, m := range measurer.metrics() {
-		m.ParseNode(n)
+func (measurer *MeasurerWMFP) parseNode(n ast.Node, weight float64) {
+	length := measurer.Halst.Length()
+	measurer.Halst.ParseNode(n)
+	if weight == 1 && !measurer.Attribute {
+		for _, m := range measurer.metrics() {
+			m.ParseNode(n)
+		}
+		return
+	}
+
+	delta := measurer.count(n)
+	counts := delta.Scale(weight)
+	measurer.weighted = measurer.weighted.Add(counts)
+	tokens := measurer.Halst.Length() - length
+	if weight != 1 {
+		measurer.suppressed = measurer.suppressed.Add(delta.Scale(1 - weight))
+		measurer.suppressedTokens += float64(tokens) * (1 - weight)
+	}
+	if measurer.Attribute {
+		measurer.contributions = append(measurer.contributions, contribution{
+			node:   n,
+			counts: counts,
+			weight: weight,
+			tokens: tokens,
+		})
 	}
 }
@@ -1,8 +1,11 @@
 package wmfp
 
 import (
+	"go/ast"
 	"go/parser"
 	"go/token"
+	"math"
+	"reflect"
 	"testing"
 
 	"github.com/bragov4ik/go-kys/pkg/arithmetic"
@@ -10,6 +13,8 @@ import (
 	"github.com/bragov4ik/go-kys/pkg/comments"
 	cyclo "github.com/bragov4ik/go-kys/pkg/cyclocomp"
 	"github.com/bragov4ik/go-kys/pkg/inline"
+	"github.com/bragov4ik/go-kys/pkg/nesting"
+	"github.com/bragov4ik/go-kys/pkg/pragma"
 )
 
 func TestWMFP(t *testing.T) {
@@ -85,9 +90,278 @@ func TestWMFP(t *testing.T) {
 	}
 
 	m.ParseFile(file)
-	var expect uint = 364
+	var expect uint = 362
 
 	if got := m.Finish(); uint(got) != expect {
 		t.Errorf("Finish = %v, want %v", got, expect)
 	}
 }
+
+func TestDirectives(t *testing.T) {
+	src := `package p
+
+func f(x int) int {
+	if x > 0 && x < 10 {
+		return x * 2
+	}
+	return 0
+}
+
+//gokys:weight 0.5
+func g(x int) int {
+	for i := 0; i < x; i++ { //gokys:ignore
+		x += i
+	}
+	return x
+}
+`
+	cfg := Config{
+		CycloComp:      cyclo.Weights{If: 1, For: 1, And: 1},
+		ArithmeticComp: arithmetic.Weights{Mul: 1, AddAssign: 1, Inc: 1},
+		InlineData:     inline.Weights{Int: 1},
+		Halstead:       1,
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives, err := pragma.Parse(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := NewMeasurerWMFP(&cfg)
+	plain.ParseFile(file)
+	if plain.Suppressed() != 0 {
+		t.Errorf("Suppressed() = %v without directives", plain.Suppressed())
+	}
+
+	cfg.Halstead = 0
+	score := func(node ast.Node, d *pragma.Directives) (float64, float64) {
+		m := NewMeasurerWMFP(&cfg)
+		m.Directives = d
+		m.Parse(node)
+		return m.Finish(), m.Suppressed()
+	}
+	g := file.Decls[1].(*ast.FuncDecl)
+	all, _ := score(g, nil)
+	// loop is measured inside of g, branches outside of functions are not counted
+	attributed := NewMeasurerWMFP(&cfg)
+	attributed.Attribute = true
+	attributed.Parse(g)
+	var loop float64
+	for _, c := range attributed.Contributions() {
+		if stmt := g.Body.List[0]; c.Node.Pos() >= stmt.Pos() && c.Node.End() <= stmt.End() {
+			loop += c.Counts.Score(&cfg)
+		}
+	}
+
+	// loop is ignored, the rest of g is halved
+	finish, suppressed := score(g, directives)
+	if want := (all - loop) * 0.5; math.Abs(finish-want) > 1e-9 {
+		t.Errorf("Finish() = %v, want %v", finish, want)
+	}
+	if math.Abs(finish+suppressed-all) > 1e-9 {
+		t.Errorf("Finish() + Suppressed() = %v, want %v", finish+suppressed, all)
+	}
+
+	cfg.Halstead = 1
+	ignored := NewMeasurerWMFP(&cfg)
+	ignored.Directives = directives
+	ignored.ParseFile(file)
+	if ignored.Finish() >= plain.Finish() || ignored.Suppressed() <= 0 {
+		t.Errorf("Finish() = %v, Suppressed() = %v, directives are not applied", ignored.Finish(), ignored.Suppressed())
+	}
+	// directives only discount halstead volume of the whole file
+	volume := plain.Counts().Halstead
+	if got := ignored.Counts().Halstead + ignored.SuppressedCounts().Halstead; math.Abs(got-volume) > 1e-9 {
+		t.Errorf("halstead with directives = %v, want split of %v", got, volume)
+	}
+}
+
+func TestFileDirective(t *testing.T) {
+	src := `//gokys:ignore
+
+package p
+
+func f(x int) int { return x * 2 }
+`
+	cfg := Config{ArithmeticComp: arithmetic.Weights{Mul: 1}, InlineData: inline.Weights{Int: 1}, Halstead: 1}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives, err := pragma.Parse(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewMeasurerWMFP(&cfg)
+	m.Directives = directives
+	m.ParseFile(file)
+	if m.Finish() != 0 || m.Suppressed() <= 0 {
+		t.Errorf("Finish() = %v, Suppressed() = %v, want ignored file", m.Finish(), m.Suppressed())
+	}
+}
+
+func TestCounts(t *testing.T) {
+	src := `package p
+
+// Sums numbers
+func f(xs []int) (sum int) {
+	for _, x := range xs {
+		if x > 0 && x%2 == 0 {
+			sum += x * 2
+		}
+	}
+	fmt.Printf("%d items\n", len(xs))
+	return
+}
+
+//gokys:weight 0.5
+type T struct {
+	A int ` + "`json:\"a\"`" + `
+	B []string
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives, err := pragma.Parse(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	measure := func(cfg *Config) MeasurerWMFP {
+		m := NewMeasurerWMFP(cfg)
+		m.Directives = directives
+		m.ParseFile(file)
+		return m
+	}
+
+	first := Config{CycloComp: cyclo.Weights{If: 1}, Halstead: 1}
+	second := Config{
+		CycloComp:      cyclo.Weights{If: 2, Rng: 1, And: 0.5},
+		Comment:        comments.Weights{Word: 0.1},
+		CodeStructComp: codestruct.Weights{Func: 3, Struct: 1, Field: 0.5, Tag: 0.2, Param: 0.1},
+		InlineData:     inline.Weights{Int: 0.5, String: 0.1, FormatString: 0.3},
+		ArithmeticComp: arithmetic.Weights{Mul: 1, Rem: 1, AddAssign: 0.5},
+		Halstead:       0.05,
+		Nesting:        nesting.Weights{Threshold: 1, Level: 2},
+	}
+	m := measure(&first)
+	counts, suppressed := m.Counts(), m.SuppressedCounts()
+	if counts.CycloComp.If != 1 || counts.CycloComp.And != 1 || counts.InlineData.FormatString != 9 {
+		t.Errorf("Counts() = %+v", counts)
+	}
+	if counts.CodeStructComp.Struct != 0.5 || suppressed.CodeStructComp.Struct != 0.5 {
+		t.Errorf("struct counts = %v, %v, want halved by directive",
+			counts.CodeStructComp.Struct, suppressed.CodeStructComp.Struct)
+	}
+
+	want := measure(&second)
+	if got := counts.Score(&second); math.Abs(got-want.Finish()) > 1e-9 {
+		t.Errorf("Counts().Score() = %v, want %v", got, want.Finish())
+	}
+	if got := suppressed.Score(&second); math.Abs(got-want.Suppressed()) > 1e-9 {
+		t.Errorf("SuppressedCounts().Score() = %v, want %v", got, want.Suppressed())
+	}
+	if got := counts.Add(counts).Scale(0.5); !reflect.DeepEqual(got, counts) {
+		t.Errorf("Add().Scale() = %+v, want %+v", got, counts)
+	}
+
+	b := counts.Breakdown(&second)
+	// for and if are nested at depths 1 and 2, scanning threshold does not matter
+	if b.CycloComp != 1+2+1+0.5 || b.Halstead != counts.Halstead*0.05 || b.Nesting != 2 || b.Assets != 0 {
+		t.Errorf("Breakdown() = %+v", b)
+	}
+	if sum := b.Add(b); sum.Total() != 2*b.Total() || sum.Halstead != 2*b.Halstead {
+		t.Errorf("Add() = %+v, want doubled %+v", sum, b)
+	}
+	parts := b.Parts()
+	if len(parts) != 9 || parts[0] != (Part{"cyclomatic", b.CycloComp}) || parts[5].Name != "halstead" {
+		t.Errorf("Parts() = %+v", parts)
+	}
+}
+
+func TestContributions(t *testing.T) {
+	src := `package p
+
+// Sums numbers
+func f(xs []int) (sum int) {
+	for _, x := range xs {
+		if x > 0 && x%2 == 0 {
+			sum += x * 2
+		}
+	}
+	return
+}
+
+//gokys:weight 0.5
+func g(x int) int {
+	return x * 3
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	directives, err := pragma.Parse(fset, file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		CycloComp:      cyclo.Weights{If: 2, Rng: 1, And: 0.5},
+		Comment:        comments.Weights{Word: 0.1},
+		CodeStructComp: codestruct.Weights{Func: 3, Param: 0.1},
+		InlineData:     inline.Weights{Int: 0.5},
+		ArithmeticComp: arithmetic.Weights{Mul: 1, Rem: 1, AddAssign: 0.5},
+		Halstead:       0.05,
+		Nesting:        nesting.Weights{Threshold: 1, Level: 3},
+	}
+	m := NewMeasurerWMFP(&cfg)
+	m.Directives = directives
+	m.Attribute = true
+	m.ParseFile(file)
+
+	var sum Counts
+	var ifNode ast.Node
+	for _, c := range m.Contributions() {
+		sum = sum.Add(c.Counts)
+		if c.Counts.CycloComp.If != 0 {
+			ifNode = c.Node
+		}
+	}
+	want := m.Counts()
+	if math.Abs(sum.Halstead-want.Halstead) > 1e-9 {
+		t.Errorf("sum of halstead contributions = %v, want %v", sum.Halstead, want.Halstead)
+	}
+	sum.Halstead = want.Halstead
+	if !reflect.DeepEqual(sum, want) {
+		t.Errorf("sum of contributions = %+v, want %+v", sum, want)
+	}
+	if _, ok := ifNode.(*ast.IfStmt); !ok {
+		t.Errorf("if is attributed to %T, want *ast.IfStmt", ifNode)
+	}
+
+	var minutes float64
+	var nested bool
+	for _, term := range want.Terms(&cfg) {
+		minutes += term.Minutes
+		if term.Name == "cyclomatic.if" && (term.Count != 1 || term.Minutes != 2) {
+			t.Errorf("Terms() contains %+v, want if counted once with weight 2", term)
+		}
+		if term.Name == "nesting.depth.2" {
+			nested = true
+			if term.Count != 1 || term.Minutes != 3 {
+				t.Errorf("Terms() contains %+v, want if at depth 2 with 1 level beyond threshold", term)
+			}
+		}
+	}
+	if math.Abs(minutes-want.Score(&cfg)) > 1e-9 || !nested {
+		t.Errorf("sum of Terms() = %v, want %v with nesting depths", minutes, want.Score(&cfg))
+	}
+}